@@ -25,6 +25,7 @@ type ListenerConfig struct {
 	Address              string
 	Tls_disable          bool
 	Tls_autoredirect     bool
+	Tls_client_auth      bool
 	Cert                 *Certificate
 	Pki_cert             *Pki_certificate
 	Lets_encrypt_address string
@@ -49,8 +50,9 @@ type VaultConfig struct {
 	Runtime_config  string
 	Approle_login   string
 	Approle_id      string
-	CA_cert			string
-	CA_path			string
+	CA_cert         string
+	CA_path         string
+	Audit_log       string
 }
 
 func LoadConfigFile(path string) (*Config, error) {
@@ -212,6 +214,7 @@ func parseListener(result *Config, listener *ast.ObjectItem) error {
 		"address",
 		"tls_disable",
 		"tls_autoredirect",
+		"tls_client_auth",
 		"certificate",
 		"pki_certificate",
 		"lets_encrypt",
@@ -258,10 +261,28 @@ func parseListener(result *Config, listener *ast.ObjectItem) error {
 		}
 	}
 
+	if raw, ok := temp["tls_client_auth"]; ok {
+		if b, ok := raw.(int); ok {
+			if b == 1 {
+				result.Listener.Tls_client_auth = true
+			} else if b != 0 {
+				return fmt.Errorf("listener.%s: tls_client_auth must be 0 or 1", key)
+			}
+		} else {
+			return fmt.Errorf("listener.%s: tls_client_auth must be 0 or 1", key)
+		}
+	}
+
 	// check for configuration conflicts
 	if result.Listener.Tls_disable && result.Listener.Tls_autoredirect {
 		return fmt.Errorf("listener.%s: tls_autoredirect conflicts with tls_disable", key)
 	}
+	if result.Listener.Tls_disable && result.Listener.Tls_client_auth {
+		return fmt.Errorf("listener.%s: tls_client_auth conflicts with tls_disable", key)
+	}
+	if _, exists := temp["lets_encrypt"]; exists && result.Listener.Tls_client_auth {
+		return fmt.Errorf("listener.%s: tls_client_auth conflicts with lets_encrypt", key)
+	}
 
 	cert_options := []string{"certificate", "pki_certificate", "lets_encrypt"}
 
@@ -435,6 +456,7 @@ func parseVault(result *Config, vault *ast.ObjectItem) error {
 		"approle_id",
 		"ca_cert",
 		"ca_path",
+		"audit_log",
 	}
 	if err := checkHCLKeys(vault.Val, valid); err != nil {
 		return fmt.Errorf("vault.%s: %s", key, err.Error())
@@ -499,5 +521,11 @@ func parseVault(result *Config, vault *ast.ObjectItem) error {
 		result.Vault.CA_path = ""
 	}
 
+	if auditLog, ok := m["audit_log"]; ok && auditLog != "" {
+		result.Vault.Audit_log = auditLog
+	} else {
+		result.Vault.Audit_log = ""
+	}
+
 	return nil
 }
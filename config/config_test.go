@@ -157,6 +157,21 @@ func TestConfigParser(t *testing.T) {
 		So(cfg, ShouldBeNil)
 	})
 
+	Convey("Parser should reject invalid listener - tls_client_auth without tls", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+				tls_disable      = 1
+				tls_client_auth  = 1
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
 	Convey("Parser should reject invalid strings - no vault config", t, func() {
 		cfg, err := ParseConfig(`
 			listener "tcp" {
@@ -236,6 +251,21 @@ func TestConfigParser(t *testing.T) {
 		So(cfg, ShouldBeNil)
 	})
 
+	Convey("Parser should accept an audit log path", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+				tls_disable      = 1
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+				audit_log       = "/var/log/vault/audit.log"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Vault.Audit_log, ShouldEqual, "/var/log/vault/audit.log")
+	})
+
 	Convey("If tls is disabled, providing certificate config should raise errors", t, func() {
 		cfg, err := ParseConfig(`
 			listener "tcp" {
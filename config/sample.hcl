@@ -12,6 +12,11 @@ listener "tcp" {
 	# set to 1 to redirect port 80 to 443 (hard-coded port numbers)
 	tls_autoredirect = 0
 
+	# [Optional] [Default: 0] [Allowed values: 0, 1]
+	# set to 1 to ask browsers for a client certificate, so users can log in with it
+	# certificates are checked against a vault cert backend. See CertLoginRole in the run-time config
+	tls_client_auth  = 0
+
 	# Option 1: local certificate
 	certificate "local" {
 		cert_file = "/path/to/certificate.cert"
@@ -62,6 +67,11 @@ vault {
 	# [Optional] [Default: ""]
 	# See above. This should be a path to a directory instead of a single cert
 	ca_path         = ""
+
+	# [Optional] [Default: ""]
+	# Path to a vault file audit log that goldfish can read, e.g. "/var/log/vault/audit.log"
+	# Enables the audit log viewer. The log must be on the same host as goldfish
+	audit_log       = ""
 }
 
 # [Optional] [Default: 0] [Allowed values: 0, 1]
@@ -26,6 +26,16 @@ export default {
       name: 'Requests',
       path: '/requests',
       component: lazyLoading('admin/Requests')
+    },
+    {
+      name: 'Elevations',
+      path: '/elevations',
+      component: lazyLoading('admin/Elevations')
+    },
+    {
+      name: 'Leases',
+      path: '/leases',
+      component: lazyLoading('admin/Leases')
     }
   ]
 }
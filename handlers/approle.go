@@ -3,9 +3,12 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/caiyeon/goldfish/vault"
 	"github.com/labstack/echo"
 )
 
+// the approle backend's mount point is in the query param 'mount'. Empty means 'approle'
+
 func GetApproleRoles() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		// fetch auth from header or cookie
@@ -16,9 +19,76 @@ func GetApproleRoles() echo.HandlerFunc {
 		defer auth.Clear()
 
 		// fetch results
-		results, err := auth.ListApproleRoles()
+		results, err := auth.ListApproleRoles(c.QueryParam("mount"))
+		if err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": results,
+		})
+	}
+}
+
+// creates or replaces a role. The role's name is in 'roleid'
+func PostApproleRole() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var role vault.Role
+		if err := c.Bind(&role); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		if err := auth.WriteApproleRole(c.QueryParam("mount"), role); err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Role saved",
+		})
+	}
+}
+
+func GetApproleRoleID() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		result, err := auth.GetApproleRoleID(c.QueryParam("mount"), c.QueryParam("role"))
+		if err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+func GetSecretIDAccessors() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		results, err := auth.ListSecretIDAccessors(c.QueryParam("mount"), c.QueryParam("role"))
 		if err != nil {
-			return parseError(c, err)
+			return userpassError(c, err)
 		}
 
 		return c.JSON(http.StatusOK, H{
@@ -27,6 +97,70 @@ func GetApproleRoles() echo.HandlerFunc {
 	}
 }
 
+func DestroySecretIDAccessor() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// scoped struct. No other functions need to know this
+		var body struct {
+			Role     string `json:"role"`
+			Accessor string `json:"accessor"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		if err := auth.DestroySecretIDAccessor(c.QueryParam("mount"), body.Role, body.Accessor); err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Secret id destroyed",
+		})
+	}
+}
+
+// Issues a secret id for a role. It is always returned inside a wrapping token
+func IssueSecretID() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// scoped struct. No other functions need to know this
+		var body struct {
+			Role     string            `json:"role"`
+			WrapTTL  string            `json:"wrap_ttl"`
+			Metadata map[string]string `json:"metadata"`
+			CIDRList string            `json:"cidr_list"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		result, err := auth.IssueSecretID(c.QueryParam("mount"), body.Role, body.WrapTTL, body.Metadata, body.CIDRList)
+		if err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
 func DeleteApproleRole() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		// fetch auth from header or cookie
@@ -43,8 +177,8 @@ func DeleteApproleRole() echo.HandlerFunc {
 				"error": "role parameter is required",
 			})
 		}
-		if _, err := auth.DeleteRaw("auth/approle/role/" + role); err != nil {
-			return parseError(c, err)
+		if err := auth.DeleteApproleRole(c.QueryParam("mount"), role); err != nil {
+			return userpassError(c, err)
 		}
 
 		return c.JSON(http.StatusOK, H{
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// Lists enabled audit devices and their options
+// enabling and disabling devices goes through the request approval flow
+func GetAuditDevices() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		result, err := auth.ListAuditDevices()
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+// Searches the configured vault audit log
+// a plaintext value can be given to find entries containing its hmac
+func SearchAuditLog() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// scoped struct. No other functions need to know this
+		// the body is used instead of query params, to keep plaintext values out of access logs
+		var body struct {
+			Since         string `json:"since"`
+			Until         string `json:"until"`
+			Path          string `json:"path"`
+			Operation     string `json:"operation"`
+			DisplayName   string `json:"display_name"`
+			RemoteAddress string `json:"remote_address"`
+			Value         string `json:"value"`
+			Limit         int    `json:"limit"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		filter := vault.AuditLogFilter{
+			Path:          body.Path,
+			Operation:     body.Operation,
+			DisplayName:   body.DisplayName,
+			RemoteAddress: body.RemoteAddress,
+			Limit:         body.Limit,
+		}
+		for name, t := range map[string]struct {
+			raw  string
+			dest *time.Time
+		}{
+			"since": {body.Since, &filter.Since},
+			"until": {body.Until, &filter.Until},
+		} {
+			if t.raw == "" {
+				continue
+			}
+			parsed, err := time.Parse(time.RFC3339, t.raw)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "'" + name + "' must be an RFC3339 timestamp",
+				})
+			}
+			*t.dest = parsed
+		}
+
+		if body.Value != "" {
+			hash, err := auth.AuditHash(body.Value)
+			if err != nil {
+				if strings.Contains(err.Error(), "Code:") {
+					return parseError(c, err)
+				}
+				return c.JSON(http.StatusBadRequest, H{
+					"error": err.Error(),
+				})
+			}
+			filter.Hash = hash
+		}
+
+		result, err := auth.SearchAuditLog(filter)
+		if err != nil {
+			if strings.Contains(err.Error(), "Code:") {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+			"hash":   filter.Hash,
+		})
+	}
+}
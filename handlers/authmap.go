@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// github, okta and radius mappings share these handlers
+// the backend's mount point is in the query param 'mount'. Empty means the backend's name
+
+func GetAuthMappings(backend, kind string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// fetch results
+		results, err := auth.ListAuthMappings(backend, kind, c.QueryParam("mount"))
+		if err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": results,
+		})
+	}
+}
+
+func PostAuthMapping(backend, kind string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var mapping vault.AuthMapping
+		if err := c.Bind(&mapping); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		if err := auth.WriteAuthMapping(backend, kind, c.QueryParam("mount"), mapping); err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Mapping saved",
+		})
+	}
+}
+
+func DeleteAuthMapping(backend, kind string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		if err := auth.DeleteAuthMapping(backend, kind, c.QueryParam("mount"), c.QueryParam("name")); err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Mapping deleted",
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// the cert backend's mount point is in the query param 'mount'. Empty means 'cert'
+
+func GetTrustedCerts() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// fetch results
+		results, err := auth.ListTrustedCerts(c.QueryParam("mount"))
+		if err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": results,
+		})
+	}
+}
+
+func PostTrustedCert() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var cert vault.TrustedCert
+		if err := c.Bind(&cert); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		if err := auth.WriteTrustedCert(c.QueryParam("mount"), cert); err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Certificate saved",
+		})
+	}
+}
+
+func DeleteTrustedCert() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		if err := auth.DeleteTrustedCert(c.QueryParam("mount"), c.QueryParam("name")); err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Certificate deleted",
+		})
+	}
+}
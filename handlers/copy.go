@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+// Copies or moves a secret or subtree, and reports the outcome of every path
+// With 'dryrun', only reports what would happen
+func CopySecrets() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var body struct {
+			Source      string `json:"source"`
+			Destination string `json:"destination"`
+			Conflict    string `json:"conflict"`
+			Move        bool   `json:"move"`
+			DryRun      bool   `json:"dryrun"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Body must be in JSON format",
+			})
+		}
+		if body.Source == "" || body.Destination == "" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "'source' and 'destination' are required",
+			})
+		}
+		switch body.Conflict {
+		case "", "skip", "overwrite", "fail":
+		default:
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "'conflict' must be one of 'skip', 'overwrite' or 'fail'",
+			})
+		}
+
+		if body.DryRun {
+			plan, err := auth.PlanCopy(body.Source, body.Destination, body.Conflict)
+			if err != nil {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusOK, H{
+				"result": plan,
+			})
+		}
+
+		report, err := auth.CopySecrets(body.Source, body.Destination, body.Conflict, body.Move)
+		if err != nil {
+			// conflicts are reported alongside the plan, so the user can see which paths clash
+			if report != nil {
+				return c.JSON(http.StatusConflict, H{
+					"error":  err.Error(),
+					"result": report,
+				})
+			}
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": report,
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/caiyeon/goldfish/request"
+	"github.com/labstack/echo"
+)
+
+// Lists active privilege elevations, if the user can look up tokens by accessor
+func GetElevations() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		result, err := request.ListElevations(auth)
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+// Hands the requester their approved elevated token, response-wrapped
+func ClaimElevation() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		hash := c.FormValue("hash")
+		if hash == "" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "'hash' parameter is required",
+			})
+		}
+
+		wrappingToken, err := request.ClaimElevation(auth, hash)
+		if err != nil {
+			// if error contains 403 from vault, forward it to the user
+			if strings.Contains(err.Error(), "Code: 403. Errors:\n\n* permission denied") {
+				return c.JSON(http.StatusForbidden, H{
+					"error": err.Error(),
+				})
+			} else {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": err.Error(),
+				})
+			}
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": wrappingToken,
+		})
+	}
+}
+
+// Revokes an elevated token before it expires
+func RevokeElevation() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		hash := c.QueryParam("hash")
+		if hash == "" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "'hash' parameter is required",
+			})
+		}
+
+		if err := request.RevokeElevation(auth, hash); err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Elevation revoked",
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/ghodss/yaml"
+	"github.com/labstack/echo"
+)
+
+// Exports every readable secret under a path as a json or yaml document
+// If 'encrypt' is true, the document is returned as transit ciphertext
+func ExportSecrets() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		path := c.QueryParam("path")
+		if path == "" || path[len(path)-1:] != "/" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Path must be a directory ending in '/'",
+			})
+		}
+
+		format := strings.ToLower(c.QueryParam("format"))
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" && format != "yaml" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Format must be 'json' or 'yaml'",
+			})
+		}
+
+		export, skipped, err := auth.ExportSecrets(path)
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		encrypt := c.QueryParam("encrypt") == "true"
+		if format == "json" && !encrypt {
+			return c.JSON(http.StatusOK, H{
+				"result":  export,
+				"skipped": skipped,
+			})
+		}
+
+		var document []byte
+		if format == "yaml" {
+			document, err = yaml.Marshal(export)
+		} else {
+			document, err = json.Marshal(export)
+		}
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		result := string(document)
+		if encrypt {
+			if result, err = auth.EncryptTransit(c.QueryParam("key"), result); err != nil {
+				return parseError(c, err)
+			}
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result":    result,
+			"format":    format,
+			"encrypted": encrypt,
+			"skipped":   skipped,
+		})
+	}
+}
+
+// Imports a document produced by ExportSecrets. Transit ciphertext is decrypted first
+// With 'dryrun', only reports which secrets would be created or overwritten
+func ImportSecrets() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var body struct {
+			Document interface{} `json:"document"`
+			Key      string      `json:"key"`
+			Path     string      `json:"path"`
+			DryRun   bool        `json:"dryrun"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Body must be in JSON format",
+			})
+		}
+
+		// the document may be embedded as an object, or as a json/yaml/ciphertext string
+		var raw string
+		switch d := body.Document.(type) {
+		case string:
+			raw = d
+		case map[string]interface{}:
+			b, _ := json.Marshal(d)
+			raw = string(b)
+		default:
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "'document' is required",
+			})
+		}
+
+		if strings.HasPrefix(raw, "vault:v") {
+			plaintext, err := auth.DecryptTransit(body.Key, raw)
+			if err != nil {
+				return parseError(c, err)
+			}
+			raw = plaintext
+		}
+
+		export, err := decodeSecretsExport(raw)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Could not parse document: " + err.Error(),
+			})
+		}
+		if err := export.Validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		plan, err := auth.PlanImport(body.Path, export)
+		if err != nil {
+			return parseError(c, err)
+		}
+		if body.DryRun {
+			return c.JSON(http.StatusOK, H{
+				"result": plan,
+			})
+		}
+
+		written, err := auth.ImportSecrets(body.Path, export)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, H{
+				"error":   err.Error(),
+				"written": written,
+			})
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result":  plan,
+			"written": written,
+		})
+	}
+}
+
+// json documents keep their numbers intact, anything else is treated as yaml
+func decodeSecretsExport(raw string) (*vault.SecretsExport, error) {
+	var export vault.SecretsExport
+	dec := json.NewDecoder(bytes.NewBufferString(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&export); err == nil {
+		return &export, nil
+	}
+	export = vault.SecretsExport{}
+	if err := yaml.Unmarshal([]byte(raw), &export); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
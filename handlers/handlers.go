@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -129,14 +130,26 @@ func Login() echo.HandlerFunc {
 				"error": "Invalid auth format",
 			})
 		}
-		if auth.Type == "" || auth.ID == "" {
+		// certificate logins are proven by the tls connection, not the body
+		isCert := strings.ToLower(auth.Type) == "cert"
+		if auth.Type == "" || (auth.ID == "" && !isCert) {
 			return c.JSON(http.StatusBadRequest, H{
 				"error": "Empty authentication",
 			})
 		}
 
 		// verify auth details and create client access token
-		data, err := auth.Login()
+		var data map[string]interface{}
+		var err error
+		if isCert {
+			var peers []*x509.Certificate
+			if state := c.Request().TLS; state != nil {
+				peers = state.PeerCertificates
+			}
+			data, err = auth.CertLogin(peers)
+		} else {
+			data, err = auth.Login()
+		}
 		if err != nil {
 			return parseError(c, err)
 		}
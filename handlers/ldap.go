@@ -3,9 +3,12 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/caiyeon/goldfish/vault"
 	"github.com/labstack/echo"
 )
 
+// the ldap backend's mount point is in the query param 'mount'. Empty means 'ldap'
+
 func GetLDAPGroups() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		// fetch auth from header or cookie
@@ -16,9 +19,9 @@ func GetLDAPGroups() echo.HandlerFunc {
 		defer auth.Clear()
 
 		// fetch results
-		results, err := auth.ListLDAPGroups()
+		results, err := auth.ListLDAPGroups(c.QueryParam("mount"))
 		if err != nil {
-			return parseError(c, err)
+			return userpassError(c, err)
 		}
 
 		return c.JSON(http.StatusOK, H{
@@ -37,9 +40,9 @@ func GetLDAPUsers() echo.HandlerFunc {
 		defer auth.Clear()
 
 		// fetch results
-		results, err := auth.ListLDAPUsers()
+		results, err := auth.ListLDAPUsers(c.QueryParam("mount"))
 		if err != nil {
-			return parseError(c, err)
+			return userpassError(c, err)
 		}
 
 		return c.JSON(http.StatusOK, H{
@@ -47,3 +50,113 @@ func GetLDAPUsers() echo.HandlerFunc {
 		})
 	}
 }
+
+func PostLDAPGroup() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var group vault.LDAPGroup
+		if err := c.Bind(&group); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		if err := auth.WriteLDAPGroup(c.QueryParam("mount"), group); err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Group saved",
+		})
+	}
+}
+
+func PostLDAPUser() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var user vault.LDAPUser
+		if err := c.Bind(&user); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		if err := auth.WriteLDAPUser(c.QueryParam("mount"), user); err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "User saved",
+		})
+	}
+}
+
+func DeleteLDAPGroup() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		if err := auth.DeleteLDAPGroup(c.QueryParam("mount"), c.QueryParam("name")); err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Group deleted",
+		})
+	}
+}
+
+func DeleteLDAPUser() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		if err := auth.DeleteLDAPUser(c.QueryParam("mount"), c.QueryParam("name")); err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "User deleted",
+		})
+	}
+}
+
+func GetLDAPConfig() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		result, err := auth.GetLDAPConfig(c.QueryParam("mount"))
+		if err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
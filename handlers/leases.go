@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+func GetLeases() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		result, err := auth.ListLeases(c.QueryParam("prefix"))
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+func RenewLease() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// scoped struct. No other functions need to know this
+		var body struct {
+			LeaseID   string `json:"lease_id"`
+			Increment int    `json:"increment"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		result, err := auth.RenewLease(body.LeaseID, body.Increment)
+		if err != nil {
+			if strings.Contains(err.Error(), "Code:") {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+func RevokeLease() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// scoped struct. No other functions need to know this
+		var body struct {
+			LeaseID string `json:"lease_id"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		if err := auth.RevokeLease(body.LeaseID); err != nil {
+			if strings.Contains(err.Error(), "Code:") {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Lease revoked",
+		})
+	}
+}
+
+// Revokes every lease under a prefix
+// A dry run reports the count and a sample. The real run must confirm the count it expects
+func RevokeLeasePrefix() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// scoped struct. No other functions need to know this
+		var body struct {
+			Prefix   string `json:"prefix"`
+			DryRun   bool   `json:"dryrun"`
+			Expected *int   `json:"expected"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		plan, err := auth.PlanRevokeLeasePrefix(body.Prefix)
+		if err != nil {
+			if strings.Contains(err.Error(), "Code:") {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+		if body.DryRun {
+			return c.JSON(http.StatusOK, H{
+				"result": plan,
+			})
+		}
+
+		// leases may have been issued or expired since the dry run
+		if body.Expected == nil || *body.Expected != plan.Count {
+			return c.JSON(http.StatusConflict, H{
+				"error":  "'expected' must match the dry run count",
+				"result": plan,
+			})
+		}
+
+		if err := auth.RevokeLeasePrefix(body.Prefix); err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": plan,
+		})
+	}
+}
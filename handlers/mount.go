@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
+	"github.com/caiyeon/goldfish/vault"
 	vaultapi "github.com/hashicorp/vault/api"
 	"github.com/labstack/echo"
 )
@@ -64,3 +67,103 @@ func ConfigMount() echo.HandlerFunc {
 		})
 	}
 }
+
+func EnableMount() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// scoped struct. No other functions need to know this
+		var body struct {
+			Path string `json:"path"`
+			vault.MountRequest
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		if err := auth.Mount(body.Path, body.MountRequest); err != nil {
+			if strings.Contains(err.Error(), "Code:") {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Mounted",
+		})
+	}
+}
+
+// Unmounts or remounts a secret engine
+// A dry run reports how many secrets the engine holds. The real run must confirm that count
+func DisableMount() echo.HandlerFunc {
+	return mountChangeHandler(func(auth *vault.AuthInfo, path, to string) error {
+		return auth.Unmount(path)
+	})
+}
+
+func Remount() echo.HandlerFunc {
+	return mountChangeHandler(func(auth *vault.AuthInfo, path, to string) error {
+		if strings.Trim(to, "/") == "" {
+			return errors.New("'to' is required")
+		}
+		return auth.Remount(path, to)
+	})
+}
+
+func mountChangeHandler(change func(auth *vault.AuthInfo, path, to string) error) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// scoped struct. No other functions need to know this
+		var body struct {
+			Path     string `json:"path"`
+			To       string `json:"to"`
+			DryRun   bool   `json:"dryrun"`
+			Expected *int   `json:"expected"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		impact, err := auth.PlanUnmount(body.Path)
+		if err == nil && !body.DryRun {
+			// secrets may have been written since the dry run
+			if body.Expected == nil || *body.Expected != impact.Secrets {
+				return c.JSON(http.StatusConflict, H{
+					"error":  "'expected' must match the dry run count of secrets",
+					"result": impact,
+				})
+			}
+			err = change(auth, body.Path, body.To)
+		}
+		if err != nil {
+			if strings.Contains(err.Error(), "Code:") {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": impact,
+		})
+	}
+}
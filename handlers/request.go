@@ -4,9 +4,8 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/caiyeon/goldfish/notify"
 	"github.com/caiyeon/goldfish/request"
-	"github.com/caiyeon/goldfish/slack"
-	"github.com/caiyeon/goldfish/vault"
 	"github.com/labstack/echo"
 )
 
@@ -20,7 +19,7 @@ func GetRequest() echo.HandlerFunc {
 		}
 		defer auth.Clear()
 
-		// fetch request from cubbyhole
+		// fetch request from storage
 		req, err := request.Get(auth, c.FormValue("hash"))
 		if err != nil {
 			// if error contains 403 from vault, forward it to the user
@@ -43,7 +42,7 @@ func GetRequest() echo.HandlerFunc {
 	}
 }
 
-// Adds a request to cubbyhole, that can be rejected/approved later
+// Adds a request to storage, that can be rejected/approved later
 // Requires requester to have read access to the policy
 func AddRequest() echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -86,23 +85,21 @@ func AddRequest() echo.HandlerFunc {
 			}
 		}
 
-		// if config has a slack webhook, send the hash (aka change ID) to the channel
-		conf := vault.GetConfig()
-		if conf.SlackWebhook != "" {
-			// send a message using webhook
-			err = slack.PostMessageWebhook(
-				conf.SlackChannel,
-				"A new policy change request has been submitted",
-				"Request ID: \n*"+hash+"*",
-				conf.SlackWebhook,
-			)
-			// change request is fine, just let the frontend know it wasn't slack'd
-			if err != nil {
-				return c.JSON(http.StatusOK, H{
-					"result": hash,
-					"error":  "Could not send to slack webhook",
-				})
-			}
+		// notify configured channels of the hash (aka change ID)
+		t, _ := params["Type"].(string)
+		if t == "" {
+			t, _ = params["type"].(string)
+		}
+		if err := request.Notify(notify.Event{
+			Kind:        notify.EventCreated,
+			RequestID:   hash,
+			RequestType: strings.ToLower(t),
+		}); err != nil {
+			// change request is fine, just let the frontend know it wasn't sent
+			return c.JSON(http.StatusOK, H{
+				"result": hash,
+				"error":  "Could not send notification: " + err.Error(),
+			})
 		}
 
 		// if all is good, return hash
@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/caiyeon/goldfish/vault"
 	"github.com/labstack/echo"
@@ -32,17 +36,63 @@ func GetSecrets() echo.HandlerFunc {
 					"path":   path,
 				})
 			}
-		} else {
+		}
+
+		mount, err := auth.KVMount(path)
+		if err != nil {
+			return parseError(c, err)
+		}
+		if mount.Version != 2 {
 			// reading a specific secret's key value pairs
 			if result, err := auth.ReadSecret(path); err != nil {
 				return parseError(c, err)
 			} else {
 				return c.JSON(http.StatusOK, H{
-					"result": result,
-					"path":   path,
+					"result":     result,
+					"path":       path,
+					"kv_version": mount.Version,
 				})
 			}
 		}
+
+		// kv v2 secrets can be read at a specific version
+		version := 0
+		if raw := c.QueryParam("version"); raw != "" {
+			if version, err = strconv.Atoi(raw); err != nil || version < 0 {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "'version' must be a non-negative integer",
+				})
+			}
+		}
+		result, versionMetadata, readErr := auth.ReadSecretVersion(path, version)
+
+		// version history may not be readable by everyone who can read the data
+		metadata, err := auth.ReadSecretMetadata(path)
+		if err != nil {
+			metadata = nil
+		}
+
+		// a deleted version has no data, but its history is still needed to undelete it
+		if readErr != nil {
+			if metadata == nil {
+				return parseError(c, readErr)
+			}
+			return c.JSON(http.StatusOK, H{
+				"result":     map[string]interface{}{},
+				"path":       path,
+				"kv_version": mount.Version,
+				"metadata":   metadata,
+				"unreadable": readErr.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result":           result,
+			"path":             path,
+			"kv_version":       mount.Version,
+			"version_metadata": versionMetadata,
+			"metadata":         metadata,
+		})
 	}
 }
 
@@ -70,13 +120,40 @@ func PostSecrets() echo.HandlerFunc {
 			})
 		}
 
-		resp, err := auth.WriteSecret(path, body)
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &data); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Body must be a JSON object",
+			})
+		}
+
+		// generated values are written straight to vault, and never returned
+		generated, err := auth.GenerateSecretValues(data)
+		if err != nil {
+			// only the transit backend can fail on vault's side, everything else is bad input
+			if strings.Contains(err.Error(), "Code:") {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		resp, err := auth.WriteSecretData(path, data)
 		if err != nil {
+			// let the user know exactly which keys need fixing
+			if schemaErr, ok := err.(*vault.SchemaError); ok {
+				return c.JSON(http.StatusBadRequest, H{
+					"error":  schemaErr.Error(),
+					"fields": schemaErr.Violations,
+				})
+			}
 			return parseError(c, err)
 		}
 
 		return c.JSON(http.StatusOK, H{
-			"result": resp,
+			"result":    resp,
+			"generated": generated,
 		})
 	}
 }
@@ -90,6 +167,22 @@ func DeleteSecrets() echo.HandlerFunc {
 		}
 		defer auth.Clear()
 
+		// on kv v2 mounts, specific versions can be soft deleted
+		if raw := c.QueryParam("versions"); raw != "" {
+			versions, err := parseVersions(raw)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": err.Error(),
+				})
+			}
+			if err := auth.DeleteSecretVersions(c.QueryParam("path"), versions); err != nil {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusOK, H{
+				"result": "success",
+			})
+		}
+
 		_, err := auth.DeleteSecret(c.QueryParam("path"))
 		if err != nil {
 			return parseError(c, err)
@@ -100,3 +193,105 @@ func DeleteSecrets() echo.HandlerFunc {
 		})
 	}
 }
+
+// restores soft deleted versions of a kv v2 secret
+func UndeleteSecrets() echo.HandlerFunc {
+	return secretVersionsHandler(func(auth *vault.AuthInfo, path string, versions []int) error {
+		return auth.UndeleteSecretVersions(path, versions)
+	})
+}
+
+// permanently destroys versions of a kv v2 secret
+func DestroySecrets() echo.HandlerFunc {
+	return secretVersionsHandler(func(auth *vault.AuthInfo, path string, versions []int) error {
+		return auth.DestroySecretVersions(path, versions)
+	})
+}
+
+func secretVersionsHandler(op func(*vault.AuthInfo, string, []int) error) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		path := c.QueryParam("path")
+		if path == "" || path[len(path)-1:] == "/" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Path must not be empty or end in '/'",
+			})
+		}
+
+		versions, err := parseVersions(c.FormValue("versions"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		if err := op(auth, path, versions); err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "success",
+		})
+	}
+}
+
+// parses a comma delimited list of version numbers, e.g. "1,2,5"
+func parseVersions(raw string) ([]int, error) {
+	var versions []int
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, errors.New("'versions' must be a comma delimited list of positive integers")
+		}
+		versions = append(versions, n)
+	}
+	if len(versions) == 0 {
+		return nil, errors.New("'versions' parameter is required")
+	}
+	return versions, nil
+}
+
+// Compares two secrets, or two subtrees, with the user's own token
+// Values are masked unless 'reveal' is true
+func DiffSecrets() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		from := c.QueryParam("from")
+		to := c.QueryParam("to")
+		if from == "" || to == "" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "'from' and 'to' parameters are required",
+			})
+		}
+		if strings.HasSuffix(from, "/") != strings.HasSuffix(to, "/") {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Paths must both be secrets, or both be directories ending in '/'",
+			})
+		}
+
+		result, skipped, err := auth.DiffSecrets(from, to, c.QueryParam("reveal") == "true")
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result":  result,
+			"skipped": skipped,
+		})
+	}
+}
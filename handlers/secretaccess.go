@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/caiyeon/goldfish/request"
+	"github.com/labstack/echo"
+)
+
+// Lists who read which secret through an approved request, for secrets the user can read
+func GetSecretAccesses() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		result, err := request.ListSecretAccesses(auth)
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+// Reads an approved secret on the requester's behalf and hands it over, response-wrapped
+func ClaimSecretAccess() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		hash := c.FormValue("hash")
+		if hash == "" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "'hash' parameter is required",
+			})
+		}
+
+		wrappingToken, err := request.ClaimSecretAccess(auth, hash)
+		if err != nil {
+			// if error contains 403 from vault, forward it to the user
+			if strings.Contains(err.Error(), "Code: 403. Errors:\n\n* permission denied") {
+				return c.JSON(http.StatusForbidden, H{
+					"error": err.Error(),
+				})
+			} else {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": err.Error(),
+				})
+			}
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": wrappingToken,
+		})
+	}
+}
@@ -3,7 +3,11 @@ package handlers
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/caiyeon/goldfish/vault"
 	"github.com/hashicorp/vault/api"
 	"github.com/labstack/echo"
 )
@@ -61,11 +65,236 @@ func LookupTokenByAccessor() echo.HandlerFunc {
 		}
 
 		// fetch results
-		result, err := auth.LookupTokenByAccessor(b.Accessors)
+		result, failed, err := auth.LookupTokenByAccessor(b.Accessors)
 		if err != nil {
 			return parseError(c, err)
 		}
 
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+			"failed": failed,
+		})
+	}
+}
+
+// Returns a page of token details, optionally filtered and sorted
+func SearchTokens() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		filter := vault.TokenFilter{
+			Policy:      c.QueryParam("policy"),
+			DisplayName: c.QueryParam("display_name"),
+			Path:        c.QueryParam("path"),
+			Orphan:      c.QueryParam("orphan"),
+			Sort:        c.QueryParam("sort"),
+		}
+
+		// numeric parameters are optional, but must be valid if present
+		ints := map[string]*int64{
+			"min_ttl":        &filter.MinTTL,
+			"max_ttl":        &filter.MaxTTL,
+			"created_after":  &filter.CreatedAfter,
+			"created_before": &filter.CreatedBefore,
+		}
+		for name, dest := range ints {
+			if raw := c.QueryParam(name); raw != "" {
+				n, err := strconv.ParseInt(raw, 10, 64)
+				if err != nil || n < 0 {
+					return c.JSON(http.StatusBadRequest, H{
+						"error": "'" + name + "' must be a non-negative integer",
+					})
+				}
+				*dest = n
+			}
+		}
+		for name, dest := range map[string]*int{"page": &filter.Page, "per_page": &filter.PerPage} {
+			if raw := c.QueryParam(name); raw != "" {
+				n, err := strconv.Atoi(raw)
+				if err != nil || n < 1 {
+					return c.JSON(http.StatusBadRequest, H{
+						"error": "'" + name + "' must be a positive integer",
+					})
+				}
+				*dest = n
+			}
+		}
+
+		result, err := auth.SearchTokens(filter)
+		if err != nil {
+			if strings.Contains(err.Error(), "Code:") {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+// Revokes every token matching a filter or list of accessors
+// A dry run reports the count and a sample. The real run must confirm the count it expects
+func BulkRevokeTokens() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// scoped struct. No other functions need to know this
+		var body struct {
+			Accessors []string `json:"accessors"`
+			Tokens    []string `json:"tokens"`
+			Filter    struct {
+				Policy        string `json:"policy"`
+				DisplayName   string `json:"display_name"`
+				Path          string `json:"path"`
+				Orphan        string `json:"orphan"`
+				MinTTL        int64  `json:"min_ttl"`
+				MaxTTL        int64  `json:"max_ttl"`
+				CreatedAfter  int64  `json:"created_after"`
+				CreatedBefore int64  `json:"created_before"`
+				// e.g. "720h" for tokens older than 30 days
+				OlderThan string `json:"older_than"`
+			} `json:"filter"`
+			// "revoke" revokes children too, "orphan" keeps them alive
+			Mode     string `json:"mode"`
+			DryRun   bool   `json:"dryrun"`
+			Expected *int   `json:"expected"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		// vault can only orphan children when given the token itself
+		if body.Mode == "orphan" {
+			if len(body.Tokens) == 0 || len(body.Accessors) > 0 {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "Orphan mode requires a list of 'tokens', since vault cannot orphan by accessor",
+				})
+			}
+			if body.DryRun {
+				return c.JSON(http.StatusOK, H{
+					"result": H{"count": len(body.Tokens)},
+				})
+			}
+			if body.Expected == nil || *body.Expected != len(body.Tokens) {
+				return c.JSON(http.StatusConflict, H{
+					"error": "'expected' must match the dry run count",
+				})
+			}
+			results, err := auth.BulkRevokeOrphan(body.Tokens)
+			if err != nil {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusOK, H{
+				"result": results,
+			})
+		}
+		if body.Mode != "" && body.Mode != "revoke" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "'mode' must be 'revoke' or 'orphan'",
+			})
+		}
+
+		filter := vault.TokenFilter{
+			Policy:        body.Filter.Policy,
+			DisplayName:   body.Filter.DisplayName,
+			Path:          body.Filter.Path,
+			Orphan:        body.Filter.Orphan,
+			MinTTL:        body.Filter.MinTTL,
+			MaxTTL:        body.Filter.MaxTTL,
+			CreatedAfter:  body.Filter.CreatedAfter,
+			CreatedBefore: body.Filter.CreatedBefore,
+		}
+		if body.Filter.OlderThan != "" {
+			d, err := time.ParseDuration(body.Filter.OlderThan)
+			if err != nil || d <= 0 {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "'older_than' must be a positive duration, e.g. '720h'",
+				})
+			}
+			filter.CreatedBefore = time.Now().Add(-d).Unix()
+		}
+
+		plan, err := auth.PlanBulkRevoke(filter, body.Accessors)
+		if err != nil {
+			if strings.Contains(err.Error(), "Code:") {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+		if body.DryRun {
+			return c.JSON(http.StatusOK, H{
+				"result": plan,
+			})
+		}
+
+		// the set of matching tokens may have changed since the dry run
+		if body.Expected == nil || *body.Expected != plan.Count {
+			return c.JSON(http.StatusConflict, H{
+				"error":  "'expected' must match the dry run count",
+				"result": plan,
+			})
+		}
+
+		results, err := auth.BulkRevokeAccessors(plan.Accessors)
+		if err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": results,
+		})
+	}
+}
+
+// Revokes a token and all of its descendants
+// A dry run returns the token that would be revoked
+func RevokeTokenSubtree() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// scoped struct. No other functions need to know this
+		var body struct {
+			Accessor string `json:"accessor"`
+			DryRun   bool   `json:"dryrun"`
+		}
+		if err := c.Bind(&body); err != nil || body.Accessor == "" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: 'accessor' is required",
+			})
+		}
+
+		result, err := auth.RevokeTokenSubtree(body.Accessor, body.DryRun)
+		if err != nil {
+			if strings.Contains(err.Error(), "Code:") {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
 		return c.JSON(http.StatusOK, H{
 			"result": result,
 		})
@@ -185,3 +414,68 @@ func GetRole() echo.HandlerFunc {
 		})
 	}
 }
+
+// Creates or replaces a token role. A dry run validates it and previews the changes
+func PostRole() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// scoped struct. No other functions need to know this
+		var body struct {
+			Rolename string          `json:"rolename"`
+			Role     vault.TokenRole `json:"role"`
+			DryRun   bool            `json:"dryrun"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		change, err := auth.PlanTokenRole(body.Rolename, body.Role)
+		if err == nil && !body.DryRun {
+			err = auth.WriteTokenRole(body.Rolename, body.Role)
+		}
+		if err != nil {
+			if strings.Contains(err.Error(), "Code:") {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": change,
+		})
+	}
+}
+
+func DeleteRole() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		if err := auth.DeleteTokenRole(c.QueryParam("rolename")); err != nil {
+			if strings.Contains(err.Error(), "Code:") {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Role deleted",
+		})
+	}
+}
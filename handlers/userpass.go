@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
+	"github.com/caiyeon/goldfish/vault"
 	"github.com/labstack/echo"
 )
 
+// the userpass backend's mount point is in the query param 'mount'. Empty means 'userpass'
+
 func GetUserpassUsers() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		// fetch auth from header or cookie
@@ -16,7 +20,7 @@ func GetUserpassUsers() echo.HandlerFunc {
 		defer auth.Clear()
 
 		// fetch results
-		results, err := auth.ListUserpassUsers()
+		results, err := auth.ListUserpassUsers(c.QueryParam("mount"))
 		if err != nil {
 			return parseError(c, err)
 		}
@@ -27,6 +31,105 @@ func GetUserpassUsers() echo.HandlerFunc {
 	}
 }
 
+func GetUserpassUser() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		result, err := auth.GetUserpassUser(c.QueryParam("mount"), c.QueryParam("username"))
+		if err != nil {
+			return userpassError(c, err)
+		}
+		if result == nil {
+			return c.JSON(http.StatusNotFound, H{
+				"error": "User not found",
+			})
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+func CreateUserpassUser() echo.HandlerFunc {
+	return userpassWriteHandler(func(auth *vault.AuthInfo, mount, name string, u vault.UserpassUserInput) error {
+		return auth.CreateUserpassUser(mount, name, u)
+	})
+}
+
+func UpdateUserpassUser() echo.HandlerFunc {
+	return userpassWriteHandler(func(auth *vault.AuthInfo, mount, name string, u vault.UserpassUserInput) error {
+		return auth.UpdateUserpassUser(mount, name, u)
+	})
+}
+
+func userpassWriteHandler(write func(*vault.AuthInfo, string, string, vault.UserpassUserInput) error) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// scoped struct. No other functions need to know this
+		var body struct {
+			Username string `json:"username"`
+			vault.UserpassUserInput
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		if err := write(auth, c.QueryParam("mount"), body.Username, body.UserpassUserInput); err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "User saved",
+		})
+	}
+}
+
+// Sets a temporary password, which is returned inside a wrapping token to pass on to the user
+func ResetUserpassPassword() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// scoped struct. No other functions need to know this
+		var body struct {
+			Username string `json:"username"`
+			WrapTTL  string `json:"wrap_ttl"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		token, err := auth.ResetUserpassPassword(c.QueryParam("mount"), body.Username, body.WrapTTL)
+		if err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": token,
+		})
+	}
+}
+
 func DeleteUserpassUser() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		// fetch auth from header or cookie
@@ -43,8 +146,8 @@ func DeleteUserpassUser() echo.HandlerFunc {
 				"error": "username parameter is required",
 			})
 		}
-		if _, err := auth.DeleteRaw("auth/userpass/users/" + username); err != nil {
-			return parseError(c, err)
+		if err := auth.DeleteUserpassUser(c.QueryParam("mount"), username); err != nil {
+			return userpassError(c, err)
 		}
 
 		return c.JSON(http.StatusOK, H{
@@ -52,3 +155,44 @@ func DeleteUserpassUser() echo.HandlerFunc {
 		})
 	}
 }
+
+// vault errors are relayed, anything else is a bad request
+func userpassError(c echo.Context, err error) error {
+	if strings.Contains(err.Error(), "Code:") {
+		return parseError(c, err)
+	}
+	return c.JSON(http.StatusBadRequest, H{
+		"error": err.Error(),
+	})
+}
+
+// Lets a userpass user change their own password. The current password is verified first
+func ChangeOwnPassword() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// scoped struct. No other functions need to know this
+		var body struct {
+			Current string `json:"current_password"`
+			New     string `json:"new_password"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid body: " + err.Error(),
+			})
+		}
+
+		if err := auth.ChangeOwnPassword(body.Current, body.New); err != nil {
+			return userpassError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Password changed",
+		})
+	}
+}
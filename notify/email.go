@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// bounds a whole delivery, from dialing the smtp server to quitting
+var smtpTimeout = 10 * time.Second
+
+// plain smtp email. STARTTLS is used automatically if the server offers it
+type Email struct {
+	Address  string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (n *Email) Name() string {
+	return "email"
+}
+
+func (n *Email) Notify(e Event) error {
+	if n.Address == "" || n.From == "" || len(n.To) == 0 {
+		return errors.New("Address, sender and recipients must be configured")
+	}
+
+	host, _, err := net.SplitHostPort(n.Address)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+
+	return n.send(host, auth, n.message(e))
+}
+
+// does what smtp.SendMail does, but a slow or unresponsive server can't block forever
+func (n *Email) send(host string, auth smtp.Auth, msg []byte) error {
+	conn, err := (&net.Dialer{Timeout: smtpTimeout}).Dial("tcp", n.Address)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("Server does not support authentication")
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(n.From); err != nil {
+		return err
+	}
+	for _, to := range n.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// builds an RFC 5322 message with CRLF line endings
+func (n *Email) message(e Event) []byte {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	headers := []string{
+		"From: " + n.From,
+		"To: " + strings.Join(n.To, ", "),
+		"Subject: [Goldfish] " + e.Title(),
+		"Date: " + e.Time.Format("Mon, 02 Jan 2006 15:04:05 -0700"),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	body := strings.Replace(e.Text(), "*", "", -1)
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" +
+		strings.Replace(body, "\n", "\r\n", -1) + "\r\n")
+}
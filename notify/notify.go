@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// kinds of events a request can go through in its lifetime
+const (
+	EventCreated   = "created"
+	EventProgress  = "progress"
+	EventCompleted = "completed"
+	EventRejected  = "rejected"
+	EventFailed    = "failed"
+)
+
+var iconURL = "https://github.com/Caiyeon/goldfish/raw/master/frontend/client/assets/logo_small.png"
+
+// http client used by all webhook based notifiers
+var httpClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+type Event struct {
+	Kind        string    `json:"kind"`
+	RequestID   string    `json:"request_id"`
+	RequestType string    `json:"request_type"`
+	Requester   string    `json:"requester"`
+	Progress    int       `json:"progress"`
+	Required    int       `json:"required"`
+	Error       string    `json:"error,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// a notification channel that can be told about request events
+type Notifier interface {
+	Name() string
+	Notify(Event) error
+}
+
+// a short human readable headline for the event
+func (e Event) Title() string {
+	switch e.Kind {
+	case EventCreated:
+		return fmt.Sprintf("A new %s change request has been submitted", e.RequestType)
+	case EventProgress:
+		return fmt.Sprintf("A %s change request has received an approval", e.RequestType)
+	case EventCompleted:
+		return fmt.Sprintf("A %s change request has been approved and completed", e.RequestType)
+	case EventRejected:
+		return fmt.Sprintf("A %s change request has been rejected", e.RequestType)
+	case EventFailed:
+		return fmt.Sprintf("A %s change request has failed", e.RequestType)
+	default:
+		return fmt.Sprintf("A %s change request was updated", e.RequestType)
+	}
+}
+
+// the details of the event, formatted as markdown
+func (e Event) Text() string {
+	text := "Request ID: \n*" + e.RequestID + "*"
+	if e.Requester != "" {
+		text += "\nRequester: " + e.Requester
+	}
+	if e.Kind == EventProgress {
+		text += fmt.Sprintf("\nProgress: %d/%d", e.Progress, e.Required)
+	}
+	if e.Error != "" {
+		text += "\nError: " + e.Error
+	}
+	return text
+}
+
+// sends the event to every notifier, collecting errors along the way
+// a failing notifier does not prevent the rest from being notified
+func Send(notifiers []Notifier, e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	var multierr error
+	for _, n := range notifiers {
+		if err := n.Notify(e); err != nil {
+			multierr = multierror.Append(multierr, errors.New(n.Name()+": "+err.Error()))
+		}
+	}
+	return multierr
+}
+
+// posts a json payload, with optional extra headers
+// any response outside of the 2xx range is treated as an error
+func postJSON(url string, payload interface{}, headers map[string]string) error {
+	body, ok := payload.([]byte)
+	if !ok {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Webhook responded with status code %d", resp.StatusCode)
+	}
+	return nil
+}
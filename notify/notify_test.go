@@ -0,0 +1,224 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// records the last request received by a stand-in webhook server
+type recorder struct {
+	header http.Header
+	body   []byte
+	status int
+}
+
+func (r *recorder) server() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.header = req.Header
+		r.body, _ = ioutil.ReadAll(req.Body)
+		if r.status != 0 {
+			w.WriteHeader(r.status)
+		}
+	}))
+}
+
+// a minimal smtp server that accepts a single message and sends it down the channel
+func fakeSMTPServer() (string, chan string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	ch := make(chan string, 1)
+
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		conn.Write([]byte("220 localhost ESMTP\r\n"))
+		var data []string
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			if inData {
+				if line == "." {
+					inData = false
+					ch <- strings.Join(data, "\n")
+					conn.Write([]byte("250 OK\r\n"))
+				} else {
+					data = append(data, line)
+				}
+				continue
+			}
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO", "HELO":
+				conn.Write([]byte("250 localhost\r\n"))
+			case "DATA":
+				inData = true
+				conn.Write([]byte("354 Go ahead\r\n"))
+			case "QUIT":
+				conn.Write([]byte("221 Bye\r\n"))
+				return
+			default:
+				conn.Write([]byte("250 OK\r\n"))
+			}
+		}
+	}()
+
+	return l.Addr().String(), ch, nil
+}
+
+// a notifier that always fails
+type broken struct{}
+
+func (n *broken) Name() string       { return "broken" }
+func (n *broken) Notify(Event) error { return errors.New("always fails") }
+
+func TestNotifiers(t *testing.T) {
+	event := Event{
+		Kind:        EventProgress,
+		RequestID:   "abcdef",
+		RequestType: "policy",
+		Requester:   "fish1",
+		Progress:    1,
+		Required:    3,
+	}
+
+	Convey("Slack notifier should post to webhook", t, func() {
+		rec := &recorder{}
+		s := rec.server()
+		defer s.Close()
+
+		So((&Slack{Webhook: s.URL, Channel: "#vault"}).Notify(event), ShouldBeNil)
+
+		var payload map[string]interface{}
+		So(json.Unmarshal(rec.body, &payload), ShouldBeNil)
+		So(payload["channel"], ShouldEqual, "#vault")
+		So(payload["text"], ShouldEqual, event.Title())
+
+		Convey("Non-200 responses should be errors", func() {
+			rec.status = http.StatusNotFound
+			So((&Slack{Webhook: s.URL}).Notify(event), ShouldNotBeNil)
+		})
+	})
+
+	Convey("Generic webhook should sign the body", t, func() {
+		rec := &recorder{}
+		s := rec.server()
+		defer s.Close()
+
+		So((&Webhook{URL: s.URL, Secret: "hunter2"}).Notify(event), ShouldBeNil)
+		So(rec.header.Get("X-Goldfish-Event"), ShouldEqual, EventProgress)
+		So(rec.header.Get("X-Goldfish-Signature"), ShouldEqual, "sha256="+Sign("hunter2", rec.body))
+
+		var received Event
+		So(json.Unmarshal(rec.body, &received), ShouldBeNil)
+		So(received.RequestID, ShouldEqual, "abcdef")
+		So(received.Progress, ShouldEqual, 1)
+
+		Convey("Without a secret, no signature should be sent", func() {
+			So((&Webhook{URL: s.URL}).Notify(event), ShouldBeNil)
+			So(rec.header.Get("X-Goldfish-Signature"), ShouldBeEmpty)
+		})
+	})
+
+	Convey("Mattermost notifier should post a slack-like payload", t, func() {
+		rec := &recorder{}
+		s := rec.server()
+		defer s.Close()
+
+		So((&Mattermost{Webhook: s.URL, Channel: "vault"}).Notify(event), ShouldBeNil)
+
+		var payload map[string]interface{}
+		So(json.Unmarshal(rec.body, &payload), ShouldBeNil)
+		So(payload["channel"], ShouldEqual, "vault")
+		So(payload["text"], ShouldContainSubstring, "abcdef")
+		So(payload["text"], ShouldContainSubstring, "1/3")
+	})
+
+	Convey("Teams notifier should post a message card", t, func() {
+		rec := &recorder{}
+		s := rec.server()
+		defer s.Close()
+
+		So((&Teams{Webhook: s.URL}).Notify(event), ShouldBeNil)
+
+		var payload map[string]interface{}
+		So(json.Unmarshal(rec.body, &payload), ShouldBeNil)
+		So(payload["@type"], ShouldEqual, "MessageCard")
+		So(payload["title"], ShouldEqual, event.Title())
+	})
+
+	Convey("Email notifier should deliver a message", t, func() {
+		addr, ch, err := fakeSMTPServer()
+		So(err, ShouldBeNil)
+
+		So((&Email{
+			Address: addr,
+			From:    "goldfish@example.com",
+			To:      []string{"ops@example.com"},
+		}).Notify(event), ShouldBeNil)
+
+		msg := <-ch
+		So(msg, ShouldContainSubstring, "To: ops@example.com")
+		So(msg, ShouldContainSubstring, "Subject: [Goldfish] "+event.Title())
+		So(msg, ShouldContainSubstring, "abcdef")
+
+		Convey("Incomplete configuration should be rejected", func() {
+			So((&Email{Address: addr}).Notify(event), ShouldNotBeNil)
+		})
+	})
+
+	Convey("Email notifier should give up on a silent server", t, func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer l.Close()
+		// accept, but never greet
+		go func() {
+			if conn, err := l.Accept(); err == nil {
+				defer conn.Close()
+				time.Sleep(2 * time.Second)
+			}
+		}()
+
+		defer func(d time.Duration) { smtpTimeout = d }(smtpTimeout)
+		smtpTimeout = 100 * time.Millisecond
+
+		start := time.Now()
+		So((&Email{
+			Address: l.Addr().String(),
+			From:    "goldfish@example.com",
+			To:      []string{"ops@example.com"},
+		}).Notify(event), ShouldNotBeNil)
+		So(time.Since(start), ShouldBeLessThan, time.Second)
+	})
+
+	Convey("Send should notify every channel despite failures", t, func() {
+		rec := &recorder{}
+		s := rec.server()
+		defer s.Close()
+
+		err := Send([]Notifier{&broken{}, &Webhook{URL: s.URL}}, event)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "broken")
+		So(rec.body, ShouldNotBeEmpty)
+	})
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/caiyeon/goldfish/slack"
+)
+
+// slack incoming webhook
+type Slack struct {
+	Webhook string
+	Channel string
+}
+
+func (n *Slack) Name() string {
+	return "slack"
+}
+
+func (n *Slack) Notify(e Event) error {
+	if n.Webhook == "" {
+		return errors.New("Webhook is not configured")
+	}
+	return slack.PostMessageWebhook(n.Channel, e.Title(), e.Text(), n.Webhook)
+}
+
+// generic json webhook. The body is the event itself
+// if a secret is set, the body is signed with HMAC-SHA256 and the hex digest is
+// sent in the X-Goldfish-Signature header, so receivers can verify the sender
+type Webhook struct {
+	URL    string
+	Secret string
+}
+
+func (n *Webhook) Name() string {
+	return "webhook"
+}
+
+func (n *Webhook) Notify(e Event) error {
+	if n.URL == "" {
+		return errors.New("URL is not configured")
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"X-Goldfish-Event": e.Kind,
+	}
+	if n.Secret != "" {
+		headers["X-Goldfish-Signature"] = "sha256=" + Sign(n.Secret, body)
+	}
+	return postJSON(n.URL, body, headers)
+}
+
+// computes the hex encoded HMAC-SHA256 of body with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// mattermost incoming webhook. Mattermost accepts a slack-like payload
+type Mattermost struct {
+	Webhook string
+	Channel string
+}
+
+func (n *Mattermost) Name() string {
+	return "mattermost"
+}
+
+func (n *Mattermost) Notify(e Event) error {
+	if n.Webhook == "" {
+		return errors.New("Webhook is not configured")
+	}
+
+	payload := map[string]interface{}{
+		"username": "Goldfish Vault UI",
+		"icon_url": iconURL,
+		"text":     "#### " + e.Title() + "\n" + e.Text(),
+	}
+	if n.Channel != "" {
+		payload["channel"] = n.Channel
+	}
+	return postJSON(n.Webhook, payload, nil)
+}
+
+// microsoft teams incoming webhook, using the legacy MessageCard format
+type Teams struct {
+	Webhook string
+}
+
+func (n *Teams) Name() string {
+	return "teams"
+}
+
+func (n *Teams) Notify(e Event) error {
+	if n.Webhook == "" {
+		return errors.New("Webhook is not configured")
+	}
+
+	payload := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    e.Title(),
+		"themeColor": themeColor(e.Kind),
+		"title":      e.Title(),
+		"text":       e.Text(),
+	}
+	return postJSON(n.Webhook, payload, nil)
+}
+
+func themeColor(kind string) string {
+	switch kind {
+	case EventCompleted:
+		return "23D160"
+	case EventRejected, EventFailed:
+		return "FF3860"
+	default:
+		return "3273DC"
+	}
+}
@@ -0,0 +1,240 @@
+package request
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/fatih/structs"
+	"github.com/mitchellh/hashstructure"
+)
+
+// enabling or disabling an audit device. Turning off auditing is high-risk,
+// so both directions need the same quorum of unseal keys as a policy change
+type AuditRequest struct {
+	Type string
+	// "enable" or "disable"
+	Action      string
+	Path        string
+	DeviceType  string
+	Description string
+	// for a disable request, these are the settings of the device being disabled
+	Options       map[string]string
+	Requester     string
+	RequesterHash string
+	Required      int
+	Progress      int `hash:"ignore"`
+}
+
+func (r AuditRequest) IsRootOnly() bool {
+	return true
+}
+
+// constructs the request from limited fields and returns the hash
+// raw must contain keys: 'action' and 'path'
+// enable requests must also contain 'devicetype', and can contain 'description' and 'options'
+func CreateAuditRequest(auth *vault.AuthInfo, raw map[string]interface{}) (*AuditRequest, string, error) {
+	r := &AuditRequest{}
+	r.Type = "audit"
+
+	if temp, ok := raw["action"]; ok {
+		r.Action, _ = temp.(string)
+	}
+	if temp, ok := raw["path"]; ok {
+		r.Path, _ = temp.(string)
+	}
+	r.Path = strings.Trim(r.Path, "/")
+	if r.Path == "" {
+		return nil, "", errors.New("'path' is required")
+	}
+
+	// requester must be able to see the current audit devices
+	devices, err := auth.ListAuditDevices()
+	if err != nil {
+		return nil, "", err
+	}
+	current, exists := devices[r.Path+"/"]
+
+	switch r.Action {
+	case "enable":
+		if exists {
+			return nil, "", errors.New("An audit device is already enabled at " + r.Path)
+		}
+		if temp, ok := raw["devicetype"]; ok {
+			r.DeviceType, _ = temp.(string)
+		}
+		if temp, ok := raw["description"]; ok {
+			r.Description, _ = temp.(string)
+		}
+		r.Options = make(map[string]string)
+		if temp, ok := raw["options"]; ok && temp != nil {
+			opts, ok := temp.(map[string]interface{})
+			if !ok {
+				return nil, "", errors.New("'options' must be an object of strings")
+			}
+			for k, v := range opts {
+				if r.Options[k], ok = v.(string); !ok {
+					return nil, "", errors.New("'options' must be an object of strings")
+				}
+			}
+		}
+		if err := vault.ValidateAuditDevice(r.Path, r.DeviceType, r.Options); err != nil {
+			return nil, "", err
+		}
+
+	case "disable":
+		if !exists {
+			return nil, "", errors.New("No audit device is enabled at " + r.Path)
+		}
+		r.DeviceType = current.Type
+		r.Description = current.Description
+		r.Options = current.Options
+		if r.Options == nil {
+			r.Options = make(map[string]string)
+		}
+
+	default:
+		return nil, "", errors.New("'action' must be 'enable' or 'disable'")
+	}
+
+	// collect requester's information
+	self, err := auth.LookupSelf()
+	if err != nil {
+		return nil, "", err
+	}
+	if self == nil {
+		return nil, "", errors.New("Could not confirm requester identity")
+	}
+	r.Requester = self.Data["display_name"].(string)
+	r.RequesterHash = fmt.Sprintf("%x", sha256.Sum256([]byte(r.Requester)))
+
+	// collect vault sys info
+	status, err := vault.GenerateRootStatus()
+	if err != nil {
+		return nil, "", err
+	}
+	r.Required = status.Required
+	r.Progress = 0
+
+	// calculate hash
+	hash_uint64, err := hashstructure.Hash(r, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	hash := strconv.FormatUint(hash_uint64, 16)
+	if hash == "" {
+		return nil, "", errors.New("Failed to hash request")
+	}
+
+	return r, hash, nil
+}
+
+// verifies user can see audit devices, and that the change still makes sense
+func (r *AuditRequest) Verify(auth *vault.AuthInfo) error {
+	devices, err := auth.ListAuditDevices()
+	if err != nil {
+		return err
+	}
+	current, exists := devices[r.Path+"/"]
+
+	switch r.Action {
+	case "enable":
+		if exists {
+			return errors.New("An audit device has been enabled at " + r.Path + " since request was made")
+		}
+	case "disable":
+		if !exists {
+			return errors.New("Audit device at " + r.Path + " has already been disabled")
+		}
+		if current.Type != r.DeviceType {
+			return errors.New("Audit device at " + r.Path + " has been changed since request was made")
+		}
+	default:
+		return errors.New("Invalid audit request action")
+	}
+
+	// if vault's key count has changed, the request is invalid
+	if status, err := vault.GenerateRootStatus(); err != nil {
+		return err
+	} else if status.Required != r.Required {
+		return errors.New("Request outdated due to vault rekey")
+	}
+
+	return nil
+}
+
+// provides an unseal key as an approval to a request
+// if there are sufficient unseal keys, attempt to roll the change
+func (r *AuditRequest) Approve(hash string, unsealKey string) error {
+	if unsealKey == "" {
+		return errors.New("Unseal key cannot be empty")
+	}
+
+	// append unseal key to storage
+	wrappingTokens, err := appendUnseal(hash, unsealKey)
+	if err != nil {
+		return err
+	}
+
+	// if there aren't enough unseals yet, update progress
+	if r.Required > len(wrappingTokens) {
+		r.Progress = len(wrappingTokens)
+		err = vault.WriteToStorage("requests/"+hash, structs.Map(r))
+		return err
+	}
+
+	// prepare cleanup
+	r.Progress = 0
+	defer vault.DeleteFromStorage("unseal_wrapping_tokens/" + hash)
+
+	// unwrap the unseal keys
+	unseals, err := unwrapUnseals(wrappingTokens)
+	if err != nil {
+		vault.WriteToStorage("requests/"+hash, structs.Map(r))
+		return errors.New("Progress has been reset: " + err.Error())
+	}
+
+	// generate root token
+	rootToken, err := generateRootToken(unseals)
+	if err != nil {
+		vault.WriteToStorage("requests/"+hash, structs.Map(r))
+		return errors.New("Progress has been reset: " + err.Error())
+	}
+	var rootAuth = &vault.AuthInfo{
+		Type: "token",
+		ID:   rootToken,
+	}
+
+	// update progress
+	r.Progress = r.Required
+
+	// prepare cleanup
+	defer vault.DeleteFromStorage("requests/" + hash)
+	defer rootAuth.RevokeSelf()
+
+	// make requested change
+	if r.Action == "enable" {
+		err = rootAuth.EnableAuditDevice(r.Path, r.DeviceType, r.Description, r.Options)
+	} else {
+		err = rootAuth.DisableAuditDevice(r.Path)
+	}
+	if err != nil {
+		return errors.New(err.Error() + " Request has been deleted.")
+	}
+
+	return nil
+}
+
+// purges the request entry and unseal keys from goldfish's storage
+func (r *AuditRequest) Reject(auth *vault.AuthInfo, hash string) error {
+	if err := vault.DeleteFromStorage("unseal_wrapping_tokens/" + hash); err != nil {
+		return err
+	}
+	if err := vault.DeleteFromStorage("requests/" + hash); err != nil {
+		return err
+	}
+	return nil
+}
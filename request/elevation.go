@@ -0,0 +1,495 @@
+package request
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/fatih/structs"
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/hashstructure"
+	"github.com/mitchellh/mapstructure"
+)
+
+// elevations longer than this are refused, unless run-time config says otherwise
+const defaultMaxElevationTTL = 24 * time.Hour
+
+type ElevationRequest struct {
+	Type          string
+	Policies      []string
+	TTL           string
+	Orphan        string
+	Role          string
+	Justification string
+	Requester     string
+	RequesterHash string
+	// only the holder of this identity can claim the elevated token
+	RequesterID string
+	Required    int
+	Progress    int `hash:"ignore"`
+
+	// set when approving. A child elevation is created under this token
+	approver *vault.AuthInfo
+}
+
+// an approved elevation, tracked until it expires or is revoked
+type Elevation struct {
+	RequestID     string
+	Accessor      string
+	Policies      []string
+	TTL           string
+	Justification string
+	Requester     string
+	RequesterHash string
+	RequesterID   string
+	Approved      string
+	Expires       string
+	// delivered to the requester once, then cleared
+	WrappingToken string `json:"-"`
+}
+
+func (r ElevationRequest) IsRootOnly() bool {
+	return false
+}
+
+// constructs the request from limited fields and returns the hash
+// raw must contain keys: 'policies', 'ttl' and 'justification', and can contain 'orphan', 'role'
+func CreateElevationRequest(auth *vault.AuthInfo, raw map[string]interface{}) (*ElevationRequest, string, error) {
+	r := &ElevationRequest{}
+	r.Type = "elevation"
+
+	// policies can be a list or a comma delimited string
+	switch temp := raw["policies"].(type) {
+	case string:
+		r.Policies = strings.Split(temp, ",")
+	case []interface{}:
+		for _, p := range temp {
+			if s, ok := p.(string); ok {
+				r.Policies = append(r.Policies, s)
+			} else {
+				return nil, "", errors.New("'policies' must be a list of strings")
+			}
+		}
+	}
+	var policies []string
+	for _, p := range r.Policies {
+		if p = strings.TrimSpace(p); p != "" {
+			policies = append(policies, p)
+		}
+		if p == "root" {
+			return nil, "", errors.New("Elevating to the root policy is not allowed")
+		}
+	}
+	if len(policies) == 0 {
+		return nil, "", errors.New("'policies' is required")
+	}
+	r.Policies = policies
+
+	if temp, ok := raw["ttl"]; ok {
+		r.TTL, _ = temp.(string)
+	}
+	if r.TTL == "" {
+		return nil, "", errors.New("'ttl' is required")
+	}
+	if err := checkElevationTTL(r.TTL); err != nil {
+		return nil, "", err
+	}
+
+	if temp, ok := raw["justification"]; ok {
+		r.Justification, _ = temp.(string)
+	}
+	r.Justification = strings.TrimSpace(r.Justification)
+	if r.Justification == "" {
+		return nil, "", errors.New("'justification' is required")
+	}
+
+	if temp, ok := raw["orphan"]; ok {
+		switch o := temp.(type) {
+		case bool:
+			r.Orphan = strconv.FormatBool(o)
+		case string:
+			r.Orphan = o
+		default:
+			return nil, "", errors.New("'orphan' must be a boolean")
+		}
+	}
+	if r.Orphan != "" && r.Orphan != "true" && r.Orphan != "false" {
+		return nil, "", errors.New("'orphan' must be empty, 'true', or 'false'")
+	}
+
+	if temp, ok := raw["role"]; ok {
+		if r.Role, ok = temp.(string); !ok {
+			return nil, "", errors.New("'role' must be in string format")
+		}
+	}
+
+	if r.Orphan == "true" && r.Role != "" {
+		return nil, "", errors.New("'role' and 'orphan' fields are mutually exclusive")
+	}
+
+	// collect requester's information
+	self, err := auth.LookupSelf()
+	if err != nil {
+		return nil, "", err
+	}
+	if self == nil {
+		return nil, "", errors.New("Could not confirm requester identity")
+	}
+	r.Requester = self.Data["display_name"].(string)
+	r.RequesterHash = fmt.Sprintf("%x", sha256.Sum256([]byte(r.Requester)))
+	if r.RequesterID, err = requesterID(self.Data); err != nil {
+		return nil, "", err
+	}
+
+	// collect vault sys info
+	status, err := vault.GenerateRootStatus()
+	if err != nil {
+		return nil, "", err
+	}
+	r.Required = status.Required
+	r.Progress = 0
+
+	// calculate hash
+	hash_uint64, err := hashstructure.Hash(r, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	hash := strconv.FormatUint(hash_uint64, 16)
+	if hash == "" {
+		return nil, "", errors.New("Failed to hash request")
+	}
+
+	return r, hash, nil
+}
+
+// the requested duration must be positive and within the configured maximum
+func checkElevationTTL(ttl string) error {
+	d, err := time.ParseDuration(ttl)
+	if err != nil {
+		return errors.New("'ttl' could not be parsed as a duration, e.g. '30m' or '4h'")
+	}
+	if d <= 0 {
+		return errors.New("'ttl' must be greater than 0")
+	}
+
+	max := defaultMaxElevationTTL
+	if raw := vault.GetConfig().MaxElevationTTL; raw != "" {
+		if max, err = time.ParseDuration(raw); err != nil {
+			return errors.New("MaxElevationTTL in run-time config is not a valid duration")
+		}
+	}
+	if d > max {
+		return errors.New("'ttl' must not be greater than " + max.String())
+	}
+	return nil
+}
+
+// verifies approver can read every requested policy
+func (r *ElevationRequest) Verify(auth *vault.AuthInfo) error {
+	// verify user has at least default policy
+	if _, err := auth.Login(); err != nil {
+		return err
+	}
+
+	// an approver should know what they are granting
+	for _, p := range r.Policies {
+		if _, err := auth.GetPolicy(p); err != nil {
+			return err
+		}
+	}
+
+	// if request has a specific role, the approver should be able to read it
+	if r.Role != "" {
+		if _, err := auth.GetRole(r.Role); err != nil {
+			return err
+		}
+	}
+
+	// the maximum may have been lowered since the request was made
+	if err := checkElevationTTL(r.TTL); err != nil {
+		return err
+	}
+
+	// if vault's key count has changed, the request is invalid
+	if status, err := vault.GenerateRootStatus(); err != nil {
+		return err
+	} else if status.Required != r.Required {
+		return errors.New("Request outdated due to vault rekey")
+	}
+
+	return nil
+}
+
+// the token that creates the elevated token: a child elevation needs a parent that outlives
+// the generated root token, so it is created by the approver that completes the quorum
+func (r *ElevationRequest) createPath() string {
+	if r.Role != "" {
+		return "auth/token/create/" + r.Role
+	}
+	return "auth/token/create"
+}
+
+// provides an unseal token as an approval to a request
+// if there are sufficient unseal tokens, mint the elevated token
+func (r *ElevationRequest) Approve(hash string, unsealKey string) error {
+	if unsealKey == "" {
+		return errors.New("Unseal key cannot be empty")
+	}
+
+	// any approver could be the one to complete the quorum, so each must be able to create the token
+	if r.Orphan != "true" {
+		if r.approver == nil {
+			return errors.New("Could not confirm approver identity")
+		}
+		capabilities, err := r.approver.CapabilitiesSelf(r.createPath())
+		if err != nil {
+			return err
+		}
+		if !hasCapability(capabilities, "update") {
+			return errors.New("A child elevation is created under the approving token, " +
+				"which needs 'update' on " + r.createPath())
+		}
+	}
+
+	// append unseal key to storage
+	wrappingTokens, err := appendUnseal(hash, unsealKey)
+	if err != nil {
+		return err
+	}
+
+	// if there aren't enough unseals yet, update progress
+	if r.Required > len(wrappingTokens) {
+		r.Progress = len(wrappingTokens)
+		err = vault.WriteToStorage("requests/"+hash, structs.Map(r))
+		return err
+	}
+
+	// prepare cleanup
+	r.Progress = 0
+	defer vault.DeleteFromStorage("unseal_wrapping_tokens/" + hash)
+
+	// unwrap the unseal tokens
+	unseals, err := unwrapUnseals(wrappingTokens)
+	if err != nil {
+		vault.WriteToStorage("requests/"+hash, structs.Map(r))
+		return err
+	}
+
+	// generate root token
+	rootToken, err := generateRootToken(unseals)
+	if err != nil {
+		vault.WriteToStorage("requests/"+hash, structs.Map(r))
+		return err
+	}
+	var rootAuth = &vault.AuthInfo{
+		Type: "token",
+		ID:   rootToken,
+	}
+
+	// update progress
+	r.Progress = r.Required
+
+	// prepare cleanup
+	defer vault.DeleteFromStorage("requests/" + hash)
+	defer rootAuth.RevokeSelf()
+
+	// the token lives exactly as long as requested, and cannot be renewed past it
+	renewable := false
+	opts := &api.TokenCreateRequest{
+		Policies:       r.Policies,
+		TTL:            r.TTL,
+		ExplicitMaxTTL: r.TTL,
+		Renewable:      &renewable,
+		DisplayName:    "elevation-" + r.Requester,
+		Metadata: map[string]string{
+			"requester":     r.Requester,
+			"request_id":    hash,
+			"justification": r.Justification,
+		},
+	}
+
+	// the wrapping token is useless after the elevation ends anyway
+	var resp *api.Secret
+	if r.Orphan == "true" {
+		// created token would be revoked when the generated root token is revoked,
+		// so no_parent needs to be set
+		opts.NoParent = true
+		resp, err = rootAuth.CreateToken(opts, true, "", r.TTL)
+	} else {
+		// revoking the approver's token revokes the elevation too
+		resp, err = r.approver.CreateToken(opts, false, r.Role, r.TTL)
+	}
+	if err != nil {
+		return errors.New(err.Error() + " Request has been deleted.")
+	}
+	if resp == nil || resp.WrapInfo == nil {
+		return errors.New("Vault did not return a wrapped token")
+	}
+
+	// track the elevation, holding on to the wrapping token until the requester claims it
+	d, _ := time.ParseDuration(r.TTL)
+	now := time.Now().UTC()
+	e := &Elevation{
+		RequestID:     hash,
+		Accessor:      resp.WrapInfo.WrappedAccessor,
+		Policies:      r.Policies,
+		TTL:           r.TTL,
+		Justification: r.Justification,
+		Requester:     r.Requester,
+		RequesterHash: r.RequesterHash,
+		RequesterID:   r.RequesterID,
+		Approved:      now.Format(time.RFC3339),
+		Expires:       now.Add(d).Format(time.RFC3339),
+		WrappingToken: resp.WrapInfo.Token,
+	}
+	return e.save()
+}
+
+// purges the request entry and unseal tokens from goldfish's storage
+func (r *ElevationRequest) Reject(auth *vault.AuthInfo, hash string) error {
+	if err := vault.DeleteFromStorage("unseal_wrapping_tokens/" + hash); err != nil {
+		return err
+	}
+	if err := vault.DeleteFromStorage("requests/" + hash); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (e *Elevation) save() error {
+	return vault.WriteToStorage("elevations/"+e.RequestID, structs.Map(e))
+}
+
+func loadElevation(hash string) (*Elevation, error) {
+	data, err := vault.ReadFromStorage("elevations/" + hash)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, errors.New("Elevation not found")
+	}
+
+	var e Elevation
+	if err := mapstructure.Decode(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// hands the wrapped elevated token to the requester. This can only happen once
+func ClaimElevation(auth *vault.AuthInfo, hash string) (string, error) {
+	lockMap.Lock()
+	defer lockMap.Unlock()
+
+	e, err := loadElevation(hash)
+	if err != nil {
+		return "", err
+	}
+
+	// only the requester can claim the token
+	self, err := auth.LookupSelf()
+	if err != nil {
+		return "", err
+	}
+	if self == nil {
+		return "", errors.New("Could not confirm requester identity")
+	}
+	id, err := requesterID(self.Data)
+	if err != nil {
+		return "", err
+	}
+	if e.RequesterID == "" || id != e.RequesterID {
+		return "", errors.New("Only the requester can claim this elevation")
+	}
+
+	if e.WrappingToken == "" {
+		return "", errors.New("Elevation has already been claimed")
+	}
+	token := e.WrappingToken
+	e.WrappingToken = ""
+	if err := e.save(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// lists elevations that are still active, pruning those that expired or were revoked
+// the caller must be able to look up tokens by accessor
+func ListElevations(auth *vault.AuthInfo) ([]Elevation, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	// only the storage reads need the lock, vault lookups happen after releasing it
+	lockMap.Lock()
+	hashes, err := vault.GetStorage().List("elevations/")
+	if err != nil {
+		lockMap.Unlock()
+		return nil, err
+	}
+	elevations := make(map[string]*Elevation)
+	for _, hash := range hashes {
+		if e, err := loadElevation(hash); err == nil {
+			elevations[hash] = e
+		}
+	}
+	lockMap.Unlock()
+
+	results := []Elevation{}
+	gone := make(map[string]string)
+	for _, hash := range hashes {
+		e, ok := elevations[hash]
+		if !ok {
+			continue
+		}
+
+		// the caller's token is used to check if the elevated token still exists
+		resp, err := client.Logical().Write("auth/token/lookup-accessor",
+			map[string]interface{}{
+				"accessor": e.Accessor,
+			})
+		if err != nil && strings.Contains(err.Error(), "Code: 403") {
+			return nil, err
+		}
+		// only stop tracking tokens that vault confirms are gone. Any other error may be transient
+		if err != nil && strings.Contains(err.Error(), "invalid accessor") {
+			gone[hash] = e.Accessor
+			continue
+		}
+		if err != nil || resp == nil {
+			log.Println("[ERROR]: Could not look up elevation", hash+":", err)
+		}
+
+		results = append(results, *e)
+	}
+
+	if len(gone) > 0 {
+		lockMap.Lock()
+		defer lockMap.Unlock()
+		for hash, accessor := range gone {
+			// the record may have changed while the lock was released
+			if e, err := loadElevation(hash); err == nil && e.Accessor == accessor {
+				vault.DeleteFromStorage("elevations/" + hash)
+			}
+		}
+	}
+	return results, nil
+}
+
+// revokes an elevated token with the caller's token, and stops tracking it
+func RevokeElevation(auth *vault.AuthInfo, hash string) error {
+	e, err := loadElevation(hash)
+	if err != nil {
+		return err
+	}
+	if err := auth.RevokeTokenByAccessor(e.Accessor); err != nil {
+		return err
+	}
+	return vault.DeleteFromStorage("elevations/" + hash)
+}
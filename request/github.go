@@ -124,7 +124,7 @@ func CreateGithubRequest(auth *vault.AuthInfo, raw map[string]interface{}) (*Git
 		}
 	}
 
-	// if vault and github are identical, don't create the request in cubbyhole
+	// if vault and github are identical, don't create the request in storage
 	if len(r.Changes) == 0 {
 		return nil, errors.New("No changes detected")
 	}
@@ -162,9 +162,9 @@ func (r *GithubRequest) Verify(auth *vault.AuthInfo) error {
 		r.Required = status.Required
 	}
 
-	// if progress has been reset, purge unseal keys from cubbyhole
+	// if progress has been reset, purge unseal keys from storage
 	if prevProgress != r.Progress {
-		if _, err := vault.DeleteFromCubbyhole("unseal_wrapping_tokens/" + r.CommitHash); err != nil {
+		if err := vault.DeleteFromStorage("unseal_wrapping_tokens/" + r.CommitHash); err != nil {
 			return err
 		}
 	}
@@ -182,7 +182,7 @@ func (r *GithubRequest) Approve(hash string, unsealKey string) error {
 	// github requests don't rely on external provided hash
 	hash = r.CommitHash
 
-	// append unseal key to cubbyhole
+	// append unseal key to storage
 	wrappingTokens, err := appendUnseal(hash, unsealKey)
 	if err != nil {
 		return err
@@ -191,25 +191,25 @@ func (r *GithubRequest) Approve(hash string, unsealKey string) error {
 	// if there aren't enough unseals yet, update progress
 	if r.Required > len(wrappingTokens) {
 		r.Progress = len(wrappingTokens)
-		_, err = vault.WriteToCubbyhole("requests/"+hash, structs.Map(r))
+		err = vault.WriteToStorage("requests/"+hash, structs.Map(r))
 		return err
 	}
 
 	// prepare cleanup
 	r.Progress = 0
-	defer vault.DeleteFromCubbyhole("unseal_wrapping_tokens/" + hash)
+	defer vault.DeleteFromStorage("unseal_wrapping_tokens/" + hash)
 
 	// unwrap the unseal tokens
 	unseals, err := unwrapUnseals(wrappingTokens)
 	if err != nil {
-		vault.WriteToCubbyhole("requests/"+hash, structs.Map(r))
+		vault.WriteToStorage("requests/"+hash, structs.Map(r))
 		return err
 	}
 
 	// generate root token
 	rootToken, err := generateRootToken(unseals)
 	if err != nil {
-		vault.WriteToCubbyhole("requests/"+hash, structs.Map(r))
+		vault.WriteToStorage("requests/"+hash, structs.Map(r))
 		return err
 	}
 	var rootAuth = &vault.AuthInfo{
@@ -219,7 +219,7 @@ func (r *GithubRequest) Approve(hash string, unsealKey string) error {
 
 	// prepare cleanup
 	r.Progress = r.Required
-	defer vault.DeleteFromCubbyhole("requests/" + hash)
+	defer vault.DeleteFromStorage("requests/" + hash)
 	defer rootAuth.RevokeSelf()
 
 	// for each policy in diff, update it to the proposed copy
@@ -232,12 +232,12 @@ func (r *GithubRequest) Approve(hash string, unsealKey string) error {
 	return multierr
 }
 
-// purges the request entry and unseal tokens from goldfish's cubbyhole
+// purges the request entry and unseal tokens from goldfish's storage
 func (r *GithubRequest) Reject(auth *vault.AuthInfo, hash string) error {
-	if _, err := vault.DeleteFromCubbyhole("unseal_wrapping_tokens/" + hash); err != nil {
+	if err := vault.DeleteFromStorage("unseal_wrapping_tokens/" + hash); err != nil {
 		return err
 	}
-	if _, err := vault.DeleteFromCubbyhole("requests/" + hash); err != nil {
+	if err := vault.DeleteFromStorage("requests/" + hash); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,105 @@
+package request
+
+import (
+	"log"
+	"strings"
+
+	"github.com/caiyeon/goldfish/notify"
+	"github.com/caiyeon/goldfish/vault"
+)
+
+// builds the list of notification channels configured in goldfish's run-time config
+func notifiers() []notify.Notifier {
+	conf := vault.GetConfig()
+	var n []notify.Notifier
+
+	if conf.SlackWebhook != "" {
+		n = append(n, &notify.Slack{
+			Webhook: conf.SlackWebhook,
+			Channel: conf.SlackChannel,
+		})
+	}
+	if conf.WebhookURL != "" {
+		n = append(n, &notify.Webhook{
+			URL:    conf.WebhookURL,
+			Secret: conf.WebhookSecret,
+		})
+	}
+	if conf.MattermostWebhook != "" {
+		n = append(n, &notify.Mattermost{
+			Webhook: conf.MattermostWebhook,
+			Channel: conf.MattermostChannel,
+		})
+	}
+	if conf.TeamsWebhook != "" {
+		n = append(n, &notify.Teams{
+			Webhook: conf.TeamsWebhook,
+		})
+	}
+	if conf.SMTPAddress != "" {
+		var to []string
+		for _, addr := range strings.Split(conf.SMTPTo, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				to = append(to, addr)
+			}
+		}
+		n = append(n, &notify.Email{
+			Address:  conf.SMTPAddress,
+			Username: conf.SMTPUsername,
+			Password: conf.SMTPPassword,
+			From:     conf.SMTPFrom,
+			To:       to,
+		})
+	}
+
+	return n
+}
+
+// sends an event to every configured notification channel
+func Notify(e notify.Event) error {
+	n := notifiers()
+	if len(n) == 0 {
+		return nil
+	}
+	return notify.Send(n, e)
+}
+
+// notifies channels of the outcome of an approval. Failing to notify is not fatal
+func notifyApproval(hash, t, requester string, progress, required int, err error) {
+	e := notify.Event{
+		RequestID:   hash,
+		RequestType: t,
+		Requester:   requester,
+		Progress:    progress,
+		Required:    required,
+	}
+	switch {
+	case err != nil:
+		e.Kind = notify.EventFailed
+		e.Error = err.Error()
+	case progress >= required:
+		e.Kind = notify.EventCompleted
+	default:
+		e.Kind = notify.EventProgress
+	}
+	notifyAsync(e)
+}
+
+// notifies channels that a request was rejected. Failing to notify is not fatal
+func notifyRejection(hash, t, requester string) {
+	notifyAsync(notify.Event{
+		Kind:        notify.EventRejected,
+		RequestID:   hash,
+		RequestType: t,
+		Requester:   requester,
+	})
+}
+
+// approvals and rejections hold the request lock, so slow channels must not be waited on
+func notifyAsync(e notify.Event) {
+	go func() {
+		if err := Notify(e); err != nil {
+			log.Println("[ERROR]: Could not send notification:", err.Error())
+		}
+	}()
+}
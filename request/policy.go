@@ -134,7 +134,7 @@ func (r *PolicyRequest) Approve(hash string, unsealKey string) error {
 		return errors.New("Unseal key cannot be empty")
 	}
 
-	// append unseal key to cubbyhole
+	// append unseal key to storage
 	wrappingTokens, err := appendUnseal(hash, unsealKey)
 	if err != nil {
 		return err
@@ -143,25 +143,25 @@ func (r *PolicyRequest) Approve(hash string, unsealKey string) error {
 	// if there aren't enough unseals yet, update progress
 	if r.Required > len(wrappingTokens) {
 		r.Progress = len(wrappingTokens)
-		_, err = vault.WriteToCubbyhole("requests/"+hash, structs.Map(r))
+		err = vault.WriteToStorage("requests/"+hash, structs.Map(r))
 		return err
 	}
 
 	// prepare cleanup
 	r.Progress = 0
-	defer vault.DeleteFromCubbyhole("unseal_wrapping_tokens/" + hash)
+	defer vault.DeleteFromStorage("unseal_wrapping_tokens/" + hash)
 
 	// unwrap the unseal keys
 	unseals, err := unwrapUnseals(wrappingTokens)
 	if err != nil {
-		vault.WriteToCubbyhole("requests/"+hash, structs.Map(r))
+		vault.WriteToStorage("requests/"+hash, structs.Map(r))
 		return errors.New("Progress has been reset: " + err.Error())
 	}
 
 	// generate root token
 	rootToken, err := generateRootToken(unseals)
 	if err != nil {
-		vault.WriteToCubbyhole("requests/"+hash, structs.Map(r))
+		vault.WriteToStorage("requests/"+hash, structs.Map(r))
 		return errors.New("Progress has been reset: " + err.Error())
 	}
 	var rootAuth = &vault.AuthInfo{
@@ -173,7 +173,7 @@ func (r *PolicyRequest) Approve(hash string, unsealKey string) error {
 	r.Progress = r.Required
 
 	// prepare cleanup
-	defer vault.DeleteFromCubbyhole("requests/" + hash)
+	defer vault.DeleteFromStorage("requests/" + hash)
 	defer rootAuth.RevokeSelf()
 
 	// make requested change
@@ -199,12 +199,12 @@ func (r *PolicyRequest) Approve(hash string, unsealKey string) error {
 	return nil
 }
 
-// purges the request entry and unseal keys from goldfish's cubbyhole
+// purges the request entry and unseal keys from goldfish's storage
 func (r *PolicyRequest) Reject(auth *vault.AuthInfo, hash string) error {
-	if _, err := vault.DeleteFromCubbyhole("unseal_wrapping_tokens/" + hash); err != nil {
+	if err := vault.DeleteFromStorage("unseal_wrapping_tokens/" + hash); err != nil {
 		return err
 	}
-	if _, err := vault.DeleteFromCubbyhole("requests/" + hash); err != nil {
+	if err := vault.DeleteFromStorage("requests/" + hash); err != nil {
 		return err
 	}
 	return nil
@@ -1,6 +1,7 @@
 package request
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -18,7 +19,8 @@ import (
 
 // operations on the same request should not interweave,
 // a map will prevent this race condition
-var lockMap sync.Mutex
+// it is vault's storage lock, so requests also wait for storage migrations
+var lockMap = vault.StorageLock
 var lockHash = make(map[string]bool)
 
 // only one goroutine should perform vault root generation at a time
@@ -56,14 +58,14 @@ func Add(auth *vault.AuthInfo, raw map[string]interface{}) (string, error) {
 			return "", err
 		}
 
-		// lock hash in map before writing to vault cubbyhole
+		// lock hash in map before writing to storage
 		if _, locked := lockHash[hash]; locked {
 			return "", errors.New("Someone else is currently editing this request")
 		}
 		lockHash[hash] = true
 		defer delete(lockHash, hash)
 
-		_, err = vault.WriteToCubbyhole("requests/"+hash, structs.Map(req))
+		err = vault.WriteToStorage("requests/"+hash, structs.Map(req))
 		return hash, err
 
 	case "github":
@@ -76,14 +78,65 @@ func Add(auth *vault.AuthInfo, raw map[string]interface{}) (string, error) {
 			return "", err
 		}
 
-		// lock hash in map before writing to vault cubbyhole
+		// lock hash in map before writing to storage
 		if _, locked := lockHash[hash]; locked {
 			return "", errors.New("Someone else is currently editing this request")
 		}
 		lockHash[hash] = true
 		defer delete(lockHash, hash)
 
-		_, err = vault.WriteToCubbyhole("requests/"+hash, structs.Map(req))
+		err = vault.WriteToStorage("requests/"+hash, structs.Map(req))
+		return hash, err
+
+	case "elevation":
+		// construct request fields
+		req, hash, err := CreateElevationRequest(auth, raw)
+		if err != nil {
+			return "", err
+		}
+
+		// lock hash in map before writing to storage
+		if _, locked := lockHash[hash]; locked {
+			return "", errors.New("Someone else is currently editing this request")
+		}
+		lockHash[hash] = true
+		defer delete(lockHash, hash)
+
+		err = vault.WriteToStorage("requests/"+hash, structs.Map(req))
+		return hash, err
+
+	case "secret":
+		// construct request fields
+		req, hash, err := CreateSecretAccessRequest(auth, raw)
+		if err != nil {
+			return "", err
+		}
+
+		// lock hash in map before writing to storage
+		if _, locked := lockHash[hash]; locked {
+			return "", errors.New("Someone else is currently editing this request")
+		}
+		lockHash[hash] = true
+		defer delete(lockHash, hash)
+
+		err = vault.WriteToStorage("requests/"+hash, structs.Map(req))
+		return hash, err
+
+	case "audit":
+		// construct request fields
+		req, hash, err := CreateAuditRequest(auth, raw)
+		if err != nil {
+			return "", err
+		}
+
+		// lock hash in map before writing to storage
+		if _, locked := lockHash[hash]; locked {
+			return "", errors.New("Someone else is currently editing this request")
+		}
+		lockHash[hash] = true
+		defer delete(lockHash, hash)
+
+		err = vault.WriteToStorage("requests/"+hash, structs.Map(req))
 		return hash, err
 
 	default:
@@ -93,7 +146,7 @@ func Add(auth *vault.AuthInfo, raw map[string]interface{}) (string, error) {
 
 // fetches a request if it exists, and if user has authentication
 func Get(auth *vault.AuthInfo, hash string) (Request, error) {
-	// lock hash in map before reading from vault cubbyhole
+	// lock hash in map before reading from storage
 	lockMap.Lock()
 	defer lockMap.Unlock()
 	if _, locked := lockHash[hash]; locked {
@@ -102,12 +155,12 @@ func Get(auth *vault.AuthInfo, hash string) (Request, error) {
 	lockHash[hash] = true
 	defer delete(lockHash, hash)
 
-	// fetch request from cubbyhole, if it exists
-	resp, err := vault.ReadFromCubbyhole("requests/" + hash)
+	// fetch request from storage, if it exists
+	data, err := vault.ReadFromStorage("requests/" + hash)
 	if err != nil {
 		return nil, err
 	}
-	if resp == nil {
+	if data == nil {
 		// a nil response could mean this is a github request
 		if len(hash) == 40 {
 			if req, err := CreateGithubRequest(auth, map[string]interface{}{
@@ -115,7 +168,7 @@ func Get(auth *vault.AuthInfo, hash string) (Request, error) {
 			}); err != nil {
 				return nil, err
 			} else {
-				_, err = vault.WriteToCubbyhole("requests/"+hash, structs.Map(req))
+				err = vault.WriteToStorage("requests/"+hash, structs.Map(req))
 				return req, nil
 			}
 		}
@@ -125,8 +178,8 @@ func Get(auth *vault.AuthInfo, hash string) (Request, error) {
 
 	// decode secret to a request
 	t := ""
-	if typeRaw, ok := resp.Data["Type"]; !ok {
-		if typeRaw, ok = resp.Data["type"]; ok {
+	if typeRaw, ok := data["Type"]; !ok {
+		if typeRaw, ok = data["type"]; ok {
 			t, _ = typeRaw.(string)
 		}
 	} else {
@@ -137,7 +190,7 @@ func Get(auth *vault.AuthInfo, hash string) (Request, error) {
 	case "policy":
 		// decode secret into policy request
 		var req PolicyRequest
-		if err := mapstructure.Decode(resp.Data, &req); err != nil {
+		if err := mapstructure.Decode(data, &req); err != nil {
 			return nil, err
 		}
 		// verify hash
@@ -154,7 +207,7 @@ func Get(auth *vault.AuthInfo, hash string) (Request, error) {
 	case "github":
 		// decode secret into github request
 		var req GithubRequest
-		if err := mapstructure.Decode(resp.Data, &req); err != nil {
+		if err := mapstructure.Decode(data, &req); err != nil {
 			return nil, err
 		}
 		// verify user has vault privilege to read the contained policies
@@ -166,7 +219,7 @@ func Get(auth *vault.AuthInfo, hash string) (Request, error) {
 	case "token":
 		// decode secret into token creation request
 		var req TokenRequest
-		if err := mapstructure.Decode(resp.Data, &req); err != nil {
+		if err := mapstructure.Decode(data, &req); err != nil {
 			return nil, err
 		}
 		// verify user has at least default policy
@@ -175,6 +228,57 @@ func Get(auth *vault.AuthInfo, hash string) (Request, error) {
 		}
 		return &req, nil
 
+	case "elevation":
+		// decode secret into elevation request
+		var req ElevationRequest
+		if err := mapstructure.Decode(data, &req); err != nil {
+			return nil, err
+		}
+		// verify hash
+		hash_uint64, err := hashstructure.Hash(req, nil)
+		if err != nil || strconv.FormatUint(hash_uint64, 16) != hash {
+			return nil, errors.New("Hashes do not match")
+		}
+		// verify approver can read the requested policies
+		if err := req.Verify(auth); err != nil {
+			return nil, err
+		}
+		return &req, nil
+
+	case "secret":
+		// decode secret into secret access request
+		var req SecretAccessRequest
+		if err := mapstructure.Decode(data, &req); err != nil {
+			return nil, err
+		}
+		// verify hash
+		hash_uint64, err := hashstructure.Hash(req, nil)
+		if err != nil || strconv.FormatUint(hash_uint64, 16) != hash {
+			return nil, errors.New("Hashes do not match")
+		}
+		// verify approver can read the requested secret
+		if err := req.Verify(auth); err != nil {
+			return nil, err
+		}
+		return &req, nil
+
+	case "audit":
+		// decode secret into audit device request
+		var req AuditRequest
+		if err := mapstructure.Decode(data, &req); err != nil {
+			return nil, err
+		}
+		// verify hash
+		hash_uint64, err := hashstructure.Hash(req, nil)
+		if err != nil || strconv.FormatUint(hash_uint64, 16) != hash {
+			return nil, errors.New("Hashes do not match")
+		}
+		// verify audit request is still valid
+		if err := req.Verify(auth); err != nil {
+			return nil, err
+		}
+		return &req, nil
+
 	default:
 		return nil, errors.New("Invalid request type: " + t)
 	}
@@ -183,7 +287,7 @@ func Get(auth *vault.AuthInfo, hash string) (Request, error) {
 // if unseal is nonempty string, approve request with current auth
 // otherwise, add unseal to list of unseals to generate root token later
 func Approve(auth *vault.AuthInfo, hash string, unseal string) (Request, error) {
-	// lock hash in map before writing to vault cubbyhole
+	// lock hash in map before writing to storage
 	lockMap.Lock()
 	defer lockMap.Unlock()
 	if _, locked := lockHash[hash]; locked {
@@ -192,19 +296,19 @@ func Approve(auth *vault.AuthInfo, hash string, unseal string) (Request, error)
 	lockHash[hash] = true
 	defer delete(lockHash, hash)
 
-	// fetch request from cubbyhole
-	resp, err := vault.ReadFromCubbyhole("requests/" + hash)
+	// fetch request from storage
+	data, err := vault.ReadFromStorage("requests/" + hash)
 	if err != nil {
 		return nil, err
 	}
-	if resp == nil {
+	if data == nil {
 		return nil, errors.New("Request ID not found")
 	}
 
 	// decode secret to a request
 	t := ""
-	if typeRaw, ok := resp.Data["Type"]; !ok {
-		if typeRaw, ok = resp.Data["type"]; ok {
+	if typeRaw, ok := data["Type"]; !ok {
+		if typeRaw, ok = data["type"]; ok {
 			t, _ = typeRaw.(string)
 		}
 	} else {
@@ -218,7 +322,7 @@ func Approve(auth *vault.AuthInfo, hash string, unseal string) (Request, error)
 	case "policy":
 		// decode secret into policy request
 		var req PolicyRequest
-		if err := mapstructure.Decode(resp.Data, &req); err != nil {
+		if err := mapstructure.Decode(data, &req); err != nil {
 			return nil, err
 		}
 		// verify hash
@@ -230,7 +334,9 @@ func Approve(auth *vault.AuthInfo, hash string, unseal string) (Request, error)
 		if err := req.Verify(auth); err != nil {
 			return nil, err
 		}
-		if err := req.Approve(hash, unseal); err != nil {
+		err = req.Approve(hash, unseal)
+		notifyApproval(hash, req.Type, req.Requester, req.Progress, req.Required, err)
+		if err != nil {
 			return nil, err
 		}
 		return &req, nil
@@ -238,14 +344,16 @@ func Approve(auth *vault.AuthInfo, hash string, unseal string) (Request, error)
 	case "github":
 		// decode secret into github request
 		var req GithubRequest
-		if err := mapstructure.Decode(resp.Data, &req); err != nil {
+		if err := mapstructure.Decode(data, &req); err != nil {
 			return nil, err
 		}
 		// verify user has vault privileges to read contained policies
 		if err := req.Verify(auth); err != nil {
 			return nil, err
 		}
-		if err := req.Approve(hash, unseal); err != nil {
+		err = req.Approve(hash, unseal)
+		notifyApproval(req.CommitHash, req.Type, req.Requester, req.Progress, req.Required, err)
+		if err != nil {
 			return nil, err
 		}
 		return &req, nil
@@ -253,14 +361,84 @@ func Approve(auth *vault.AuthInfo, hash string, unseal string) (Request, error)
 	case "token":
 		// decode secret into token creation request
 		var req TokenRequest
-		if err := mapstructure.Decode(resp.Data, &req); err != nil {
+		if err := mapstructure.Decode(data, &req); err != nil {
 			return nil, err
 		}
 		// verify user has at least default policy
 		if err := req.Verify(auth); err != nil {
 			return nil, err
 		}
-		if err := req.Approve(hash, unseal); err != nil {
+		err = req.Approve(hash, unseal)
+		notifyApproval(hash, req.Type, req.Requester, req.Progress, req.Required, err)
+		if err != nil {
+			return nil, err
+		}
+		return &req, nil
+
+	case "elevation":
+		// decode secret into elevation request
+		var req ElevationRequest
+		if err := mapstructure.Decode(data, &req); err != nil {
+			return nil, err
+		}
+		// verify hash
+		hash_uint64, err := hashstructure.Hash(req, nil)
+		if err != nil || strconv.FormatUint(hash_uint64, 16) != hash {
+			return nil, errors.New("Hashes do not match")
+		}
+		// verify approver can read the requested policies
+		if err := req.Verify(auth); err != nil {
+			return nil, err
+		}
+		// a child elevation is created under the approver's token
+		req.approver = auth
+		err = req.Approve(hash, unseal)
+		notifyApproval(hash, req.Type, req.Requester, req.Progress, req.Required, err)
+		if err != nil {
+			return nil, err
+		}
+		return &req, nil
+
+	case "secret":
+		// decode secret into secret access request
+		var req SecretAccessRequest
+		if err := mapstructure.Decode(data, &req); err != nil {
+			return nil, err
+		}
+		// verify hash
+		hash_uint64, err := hashstructure.Hash(req, nil)
+		if err != nil || strconv.FormatUint(hash_uint64, 16) != hash {
+			return nil, errors.New("Hashes do not match")
+		}
+		// verify approver can read the requested secret
+		if err := req.Verify(auth); err != nil {
+			return nil, err
+		}
+		err = req.Approve(hash, unseal)
+		notifyApproval(hash, req.Type, req.Requester, req.Progress, req.Required, err)
+		if err != nil {
+			return nil, err
+		}
+		return &req, nil
+
+	case "audit":
+		// decode secret into audit device request
+		var req AuditRequest
+		if err := mapstructure.Decode(data, &req); err != nil {
+			return nil, err
+		}
+		// verify hash
+		hash_uint64, err := hashstructure.Hash(req, nil)
+		if err != nil || strconv.FormatUint(hash_uint64, 16) != hash {
+			return nil, errors.New("Hashes do not match")
+		}
+		// verify audit request is still valid
+		if err := req.Verify(auth); err != nil {
+			return nil, err
+		}
+		err = req.Approve(hash, unseal)
+		notifyApproval(hash, req.Type, req.Requester, req.Progress, req.Required, err)
+		if err != nil {
 			return nil, err
 		}
 		return &req, nil
@@ -272,7 +450,7 @@ func Approve(auth *vault.AuthInfo, hash string, unseal string) (Request, error)
 
 // deletes request, if user is authorized to read resource
 func Reject(auth *vault.AuthInfo, hash string) error {
-	// lock hash in map before writing to vault cubbyhole
+	// lock hash in map before writing to storage
 	lockMap.Lock()
 	defer lockMap.Unlock()
 	if _, locked := lockHash[hash]; locked {
@@ -281,19 +459,19 @@ func Reject(auth *vault.AuthInfo, hash string) error {
 	lockHash[hash] = true
 	defer delete(lockHash, hash)
 
-	// fetch request from cubbyhole
-	resp, err := vault.ReadFromCubbyhole("requests/" + hash)
+	// fetch request from storage
+	data, err := vault.ReadFromStorage("requests/" + hash)
 	if err != nil {
 		return err
 	}
-	if resp == nil {
+	if data == nil {
 		return errors.New("Request ID not found")
 	}
 
 	// decode secret to a request
 	t := ""
-	if typeRaw, ok := resp.Data["Type"]; !ok {
-		if typeRaw, ok = resp.Data["type"]; ok {
+	if typeRaw, ok := data["Type"]; !ok {
+		if typeRaw, ok = data["type"]; ok {
 			t, _ = typeRaw.(string)
 		}
 	} else {
@@ -308,7 +486,7 @@ func Reject(auth *vault.AuthInfo, hash string) error {
 	case "policy":
 		// decode secret into policy request
 		var req PolicyRequest
-		if err := mapstructure.Decode(resp.Data, &req); err != nil {
+		if err := mapstructure.Decode(data, &req); err != nil {
 			return err
 		}
 		// verify hash
@@ -317,31 +495,94 @@ func Reject(auth *vault.AuthInfo, hash string) error {
 			return errors.New("Hashes do not match")
 		}
 		// verify policy request is still valid
-		return req.Reject(auth, hash)
+		if err := req.Reject(auth, hash); err != nil {
+			return err
+		}
+		notifyRejection(hash, req.Type, req.Requester)
+		return nil
 
 	case "github":
 		// decode secret into github request
 		var req GithubRequest
-		if err := mapstructure.Decode(resp.Data, &req); err != nil {
+		if err := mapstructure.Decode(data, &req); err != nil {
 			return err
 		}
 		// verify user has vault privileges to read contained policies
 		if err := req.Verify(auth); err != nil {
 			return err
 		}
-		return req.Reject(auth, hash)
+		if err := req.Reject(auth, hash); err != nil {
+			return err
+		}
+		notifyRejection(hash, req.Type, req.Requester)
+		return nil
 
 	case "token":
 		// decode secret into token creation request
 		var req TokenRequest
-		if err := mapstructure.Decode(resp.Data, &req); err != nil {
+		if err := mapstructure.Decode(data, &req); err != nil {
 			return err
 		}
 		// verify user has at least default policy
 		if err := req.Verify(auth); err != nil {
 			return err
 		}
-		return req.Reject(auth, hash)
+		if err := req.Reject(auth, hash); err != nil {
+			return err
+		}
+		notifyRejection(hash, req.Type, req.Requester)
+		return nil
+
+	case "elevation":
+		// decode secret into elevation request
+		var req ElevationRequest
+		if err := mapstructure.Decode(data, &req); err != nil {
+			return err
+		}
+		// verify hash
+		hash_uint64, err := hashstructure.Hash(req, nil)
+		if err != nil || strconv.FormatUint(hash_uint64, 16) != hash {
+			return errors.New("Hashes do not match")
+		}
+		if err := req.Reject(auth, hash); err != nil {
+			return err
+		}
+		notifyRejection(hash, req.Type, req.Requester)
+		return nil
+
+	case "secret":
+		// decode secret into secret access request
+		var req SecretAccessRequest
+		if err := mapstructure.Decode(data, &req); err != nil {
+			return err
+		}
+		// verify hash
+		hash_uint64, err := hashstructure.Hash(req, nil)
+		if err != nil || strconv.FormatUint(hash_uint64, 16) != hash {
+			return errors.New("Hashes do not match")
+		}
+		if err := req.Reject(auth, hash); err != nil {
+			return err
+		}
+		notifyRejection(hash, req.Type, req.Requester)
+		return nil
+
+	case "audit":
+		// decode secret into audit device request
+		var req AuditRequest
+		if err := mapstructure.Decode(data, &req); err != nil {
+			return err
+		}
+		// verify hash
+		hash_uint64, err := hashstructure.Hash(req, nil)
+		if err != nil || strconv.FormatUint(hash_uint64, 16) != hash {
+			return errors.New("Hashes do not match")
+		}
+		if err := req.Reject(auth, hash); err != nil {
+			return err
+		}
+		notifyRejection(hash, req.Type, req.Requester)
+		return nil
 
 	default:
 		return errors.New("Invalid request type: " + t)
@@ -352,6 +593,26 @@ func IsRootOnly(req Request) bool {
 	return req.IsRootOnly()
 }
 
+// hashes an identity of the token's owner that they can prove again later, from a lookup-self
+// display names alone are not enough: every token-auth user is called "token"
+func requesterID(self map[string]interface{}) (string, error) {
+	id := ""
+	path, _ := self["path"].(string)
+	if entity, _ := self["entity_id"].(string); entity != "" {
+		id = "entity:" + entity
+	} else if strings.HasPrefix(path, "auth/") && !strings.HasPrefix(path, "auth/token/") {
+		// a login is bound to its backend's mount, and the user's name in it
+		name, _ := self["display_name"].(string)
+		id = "login:" + path + ":" + name
+	} else if accessor, _ := self["accessor"].(string); accessor != "" {
+		// a token that was created directly can only be identified by itself
+		id = "accessor:" + accessor
+	} else {
+		return "", errors.New("Could not confirm requester identity")
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(id))), nil
+}
+
 // attempts to generate a root token via unseal keys
 // will return error if another key generation process is underway
 func generateRootToken(unsealKeys []string) (string, error) {
@@ -403,8 +664,8 @@ func generateRootToken(unsealKeys []string) (string, error) {
 
 // writes the provided unseal in and returns a slice of all unseals in hash
 func appendUnseal(hash, unseal string) ([]string, error) {
-	// read current request from cubbyhole
-	resp, err := vault.ReadFromCubbyhole("unseal_wrapping_tokens/" + hash)
+	// read current request from storage
+	data, err := vault.ReadFromStorage("unseal_wrapping_tokens/" + hash)
 	if err != nil {
 		return nil, err
 	}
@@ -412,13 +673,13 @@ func appendUnseal(hash, unseal string) ([]string, error) {
 	var wrappingTokens []string
 
 	// if there are already unseals, read them and append
-	if resp != nil {
+	if data != nil {
 		raw := ""
-		if temp, ok := resp.Data["wrapping_tokens"]; ok {
+		if temp, ok := data["wrapping_tokens"]; ok {
 			raw, _ = temp.(string)
 		}
 		if raw == "" {
-			return nil, errors.New("Could not find key 'wrapping_tokens' in cubbyhole")
+			return nil, errors.New("Could not find key 'wrapping_tokens' in storage")
 		}
 		wrappingTokens = append(wrappingTokens, strings.Split(raw, ";")...)
 	}
@@ -434,8 +695,8 @@ func appendUnseal(hash, unseal string) ([]string, error) {
 	// add the new unseal key in
 	wrappingTokens = append(wrappingTokens, newWrappingToken)
 
-	// write the unseals back to the cubbyhole
-	_, err = vault.WriteToCubbyhole("unseal_wrapping_tokens/"+hash,
+	// write the unseals back to storage
+	err = vault.WriteToStorage("unseal_wrapping_tokens/"+hash,
 		map[string]interface{}{
 			"wrapping_tokens": strings.Trim(strings.Join(strings.Fields(fmt.Sprint(wrappingTokens)), ";"), "[]"),
 		},
@@ -7,6 +7,7 @@ import (
 
 	"github.com/caiyeon/goldfish/config"
 	"github.com/caiyeon/goldfish/vault"
+	"github.com/hashicorp/vault/api"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -37,6 +38,10 @@ func TestRequestSystem(t *testing.T) {
 		panic("Could not confirm requester identity")
 	}
 	rootAuthHash := fmt.Sprintf("%x", sha256.Sum256([]byte(self.Data["display_name"].(string))))
+	rootAuthID, err := requesterID(self.Data)
+	if err != nil {
+		panic(err)
+	}
 
 	Convey("Testing request system", t, func() {
 		Convey("Testing policy requests", func() {
@@ -202,5 +207,180 @@ func TestRequestSystem(t *testing.T) {
 			So(err, ShouldBeNil)
 			So(policies, ShouldNotContain, "abc")
 		})
+
+		Convey("Testing secret access requests", func() {
+			// goldfish's dev policy can read secret/goldfish*
+			_, err := rootAuth.WriteSecret("secret/goldfish-jit", `{"password":"hunter2"}`)
+			So(err, ShouldBeNil)
+
+			// justification is mandatory
+			_, err = Add(rootAuth, map[string]interface{}{
+				"Type": "secret",
+				"path": "secret/goldfish-jit",
+			})
+			So(err, ShouldNotBeNil)
+
+			hash, err := Add(rootAuth, map[string]interface{}{
+				"Type":          "secret",
+				"path":          "secret/goldfish-jit",
+				"justification": "incident 42",
+			})
+			So(err, ShouldBeNil)
+			So(hash, ShouldNotBeEmpty)
+
+			req, err := Get(rootAuth, hash)
+			So(err, ShouldBeNil)
+			So(req, ShouldResemble, &SecretAccessRequest{
+				Type:          "secret",
+				Path:          "secret/goldfish-jit",
+				Justification: "incident 42",
+				Requester:     "token",
+				RequesterHash: rootAuthHash,
+				RequesterID:   rootAuthID,
+				Required:      3,
+				Progress:      0,
+			})
+
+			// nothing can be claimed before approval
+			_, err = ClaimSecretAccess(rootAuth, hash)
+			So(err, ShouldNotBeNil)
+
+			for _, unseal := range unsealTokens[:3] {
+				_, err = Approve(rootAuth, hash, unseal)
+				So(err, ShouldBeNil)
+			}
+
+			// another token user shares the display name "token", but is not the requester
+			resp, err := rootAuth.CreateToken(&api.TokenCreateRequest{Policies: []string{"default"}}, false, "", "")
+			So(err, ShouldBeNil)
+			otherAuth := &vault.AuthInfo{ID: resp.Auth.ClientToken, Type: "token"}
+			_, err = ClaimSecretAccess(otherAuth, hash)
+			So(err, ShouldNotBeNil)
+
+			// the requester receives the secret, wrapped
+			wrappingToken, err := ClaimSecretAccess(rootAuth, hash)
+			So(err, ShouldBeNil)
+			data, err := vault.UnwrapData(wrappingToken)
+			So(err, ShouldBeNil)
+			So(data["password"], ShouldEqual, "hunter2")
+
+			// but only once
+			_, err = ClaimSecretAccess(rootAuth, hash)
+			So(err, ShouldNotBeNil)
+
+			// and the read is recorded
+			accesses, err := ListSecretAccesses(rootAuth)
+			So(err, ShouldBeNil)
+			So(len(accesses), ShouldEqual, 1)
+			So(accesses[0].Path, ShouldEqual, "secret/goldfish-jit")
+			So(accesses[0].Requester, ShouldEqual, "token")
+			So(accesses[0].Claimed, ShouldNotBeEmpty)
+		})
+
+		Convey("Testing secret access requests on a kv v2 mount", func() {
+			err := rootAuth.Mount("kv2", vault.MountRequest{
+				Type:    "kv",
+				Options: map[string]string{"version": "2"},
+			})
+			So(err, ShouldBeNil)
+			_, err = rootAuth.WriteSecret("kv2/app", `{"password":"hunter3"}`)
+			So(err, ShouldBeNil)
+
+			// v2 policies are written against the data path, not the logical path
+			So(rootAuth.PutPolicy("kv2-reader", `path "kv2/data/app" { capabilities = ["read"] }`), ShouldBeNil)
+			So(rootAuth.PutPolicy("kv2-logical", `path "kv2/app" { capabilities = ["read"] }`), ShouldBeNil)
+			resp, err := rootAuth.CreateToken(&api.TokenCreateRequest{Policies: []string{"kv2-reader"}}, false, "", "")
+			So(err, ShouldBeNil)
+			readerAuth := &vault.AuthInfo{ID: resp.Auth.ClientToken, Type: "token"}
+			resp, err = rootAuth.CreateToken(&api.TokenCreateRequest{Policies: []string{"kv2-logical"}}, false, "", "")
+			So(err, ShouldBeNil)
+			logicalAuth := &vault.AuthInfo{ID: resp.Auth.ClientToken, Type: "token"}
+
+			hash, err := Add(readerAuth, map[string]interface{}{
+				"Type":          "secret",
+				"path":          "kv2/app",
+				"justification": "incident 43",
+			})
+			So(err, ShouldBeNil)
+
+			// a policy on the logical path does not grant reading the secret
+			_, err = Approve(logicalAuth, hash, unsealTokens[0])
+			So(err, ShouldNotBeNil)
+
+			for _, unseal := range unsealTokens[:3] {
+				_, err = Approve(readerAuth, hash, unseal)
+				So(err, ShouldBeNil)
+			}
+
+			// the access is only listed to those who can read the secret's data
+			accesses, err := ListSecretAccesses(readerAuth)
+			So(err, ShouldBeNil)
+			So(len(accesses), ShouldEqual, 1)
+			So(accesses[0].Path, ShouldEqual, "kv2/app")
+			accesses, err = ListSecretAccesses(logicalAuth)
+			So(err, ShouldBeNil)
+			So(len(accesses), ShouldEqual, 0)
+		})
+
+		Convey("Testing audit device requests", func() {
+			// a file device needs somewhere to write
+			_, err := Add(rootAuth, map[string]interface{}{
+				"Type":       "audit",
+				"action":     "enable",
+				"path":       "goldfish-file",
+				"devicetype": "file",
+			})
+			So(err, ShouldNotBeNil)
+
+			hash, err := Add(rootAuth, map[string]interface{}{
+				"Type":       "audit",
+				"action":     "enable",
+				"path":       "goldfish-file",
+				"devicetype": "file",
+				"options":    map[string]interface{}{"file_path": "/dev/null"},
+			})
+			So(err, ShouldBeNil)
+
+			req, err := Get(rootAuth, hash)
+			So(err, ShouldBeNil)
+			So(req, ShouldResemble, &AuditRequest{
+				Type:          "audit",
+				Action:        "enable",
+				Path:          "goldfish-file",
+				DeviceType:    "file",
+				Options:       map[string]string{"file_path": "/dev/null"},
+				Requester:     "token",
+				RequesterHash: rootAuthHash,
+				Required:      3,
+				Progress:      0,
+			})
+
+			for _, unseal := range unsealTokens[:3] {
+				_, err = Approve(rootAuth, hash, unseal)
+				So(err, ShouldBeNil)
+			}
+			devices, err := rootAuth.ListAuditDevices()
+			So(err, ShouldBeNil)
+			So(devices, ShouldContainKey, "goldfish-file/")
+
+			// disabling records what is being turned off
+			hash, err = Add(rootAuth, map[string]interface{}{
+				"Type":   "audit",
+				"action": "disable",
+				"path":   "goldfish-file",
+			})
+			So(err, ShouldBeNil)
+			req, err = Get(rootAuth, hash)
+			So(err, ShouldBeNil)
+			So(req.(*AuditRequest).Options["file_path"], ShouldEqual, "/dev/null")
+
+			for _, unseal := range unsealTokens[:3] {
+				_, err = Approve(rootAuth, hash, unseal)
+				So(err, ShouldBeNil)
+			}
+			devices, err = rootAuth.ListAuditDevices()
+			So(err, ShouldBeNil)
+			So(devices, ShouldNotContainKey, "goldfish-file/")
+		})
 	})
 }
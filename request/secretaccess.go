@@ -0,0 +1,331 @@
+package request
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/fatih/structs"
+	"github.com/mitchellh/hashstructure"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	// an approved read that is not claimed within this window lapses
+	secretAccessClaimWindow = 24 * time.Hour
+	// the wrapping token handed to the requester, unless run-time config says otherwise
+	defaultSecretAccessWrapTTL = "5m"
+)
+
+// a one-time read of a single secret, by a user without standing read access
+type SecretAccessRequest struct {
+	Type          string
+	Path          string
+	Justification string
+	Requester     string
+	RequesterHash string
+	// only the holder of this identity can claim the secret
+	RequesterID string
+	Required    int
+	Progress    int `hash:"ignore"`
+}
+
+// the audit trail of a secret access request. Created on approval, updated once claimed
+type SecretAccess struct {
+	RequestID     string
+	Path          string
+	Justification string
+	Requester     string
+	RequesterHash string
+	RequesterID   string
+	Approved      string
+	Expires       string
+	// set once goldfish has read the secret on the requester's behalf
+	Claimed         string
+	WrappedAccessor string
+}
+
+func (r SecretAccessRequest) IsRootOnly() bool {
+	return false
+}
+
+// constructs the request from limited fields and returns the hash
+// raw must contain keys: 'path' and 'justification'
+func CreateSecretAccessRequest(auth *vault.AuthInfo, raw map[string]interface{}) (*SecretAccessRequest, string, error) {
+	r := &SecretAccessRequest{}
+	r.Type = "secret"
+
+	if temp, ok := raw["path"]; ok {
+		r.Path, _ = temp.(string)
+	}
+	r.Path = strings.Trim(strings.TrimSpace(r.Path), "/")
+	if r.Path == "" {
+		return nil, "", errors.New("'path' is required")
+	}
+	if strings.HasPrefix(r.Path, "cubbyhole") {
+		return nil, "", errors.New("Cubbyhole secrets cannot be requested")
+	}
+
+	if temp, ok := raw["justification"]; ok {
+		r.Justification, _ = temp.(string)
+	}
+	r.Justification = strings.TrimSpace(r.Justification)
+	if r.Justification == "" {
+		return nil, "", errors.New("'justification' is required")
+	}
+
+	// collect requester's information
+	self, err := auth.LookupSelf()
+	if err != nil {
+		return nil, "", err
+	}
+	if self == nil {
+		return nil, "", errors.New("Could not confirm requester identity")
+	}
+	r.Requester = self.Data["display_name"].(string)
+	r.RequesterHash = fmt.Sprintf("%x", sha256.Sum256([]byte(r.Requester)))
+	if r.RequesterID, err = requesterID(self.Data); err != nil {
+		return nil, "", err
+	}
+
+	// collect vault sys info
+	status, err := vault.GenerateRootStatus()
+	if err != nil {
+		return nil, "", err
+	}
+	r.Required = status.Required
+	r.Progress = 0
+
+	// calculate hash
+	hash_uint64, err := hashstructure.Hash(r, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	hash := strconv.FormatUint(hash_uint64, 16)
+	if hash == "" {
+		return nil, "", errors.New("Failed to hash request")
+	}
+
+	return r, hash, nil
+}
+
+// verifies approver can read the requested secret themselves
+func (r *SecretAccessRequest) Verify(auth *vault.AuthInfo) error {
+	// verify user has at least default policy
+	if _, err := auth.Login(); err != nil {
+		return err
+	}
+
+	capabilities, err := auth.SecretCapabilities(r.Path)
+	if err != nil {
+		return err
+	}
+	if !hasCapability(capabilities, "read") {
+		return errors.New("Code: 403. Errors:\n\n* permission denied")
+	}
+
+	// if vault's key count has changed, the request is invalid
+	if status, err := vault.GenerateRootStatus(); err != nil {
+		return err
+	} else if status.Required != r.Required {
+		return errors.New("Request outdated due to vault rekey")
+	}
+
+	return nil
+}
+
+// provides an unseal token as an approval to a request
+// if there are sufficient unseal tokens, the read is granted to the requester
+func (r *SecretAccessRequest) Approve(hash string, unsealKey string) error {
+	if unsealKey == "" {
+		return errors.New("Unseal key cannot be empty")
+	}
+
+	// append unseal key to storage
+	wrappingTokens, err := appendUnseal(hash, unsealKey)
+	if err != nil {
+		return err
+	}
+
+	// if there aren't enough unseals yet, update progress
+	if r.Required > len(wrappingTokens) {
+		r.Progress = len(wrappingTokens)
+		err = vault.WriteToStorage("requests/"+hash, structs.Map(r))
+		return err
+	}
+
+	// prepare cleanup
+	r.Progress = 0
+	defer vault.DeleteFromStorage("unseal_wrapping_tokens/" + hash)
+
+	// unwrap the unseal tokens
+	unseals, err := unwrapUnseals(wrappingTokens)
+	if err != nil {
+		vault.WriteToStorage("requests/"+hash, structs.Map(r))
+		return err
+	}
+
+	// the secret is read with goldfish's token, but generating a root token
+	// is the only way to prove the unseal keys are genuine
+	rootToken, err := generateRootToken(unseals)
+	if err != nil {
+		vault.WriteToStorage("requests/"+hash, structs.Map(r))
+		return err
+	}
+	rootAuth := &vault.AuthInfo{
+		Type: "token",
+		ID:   rootToken,
+	}
+	if err := rootAuth.RevokeSelf(); err != nil {
+		log.Println("[ERROR]: Could not revoke generated root token:", err.Error())
+	}
+
+	// update progress
+	r.Progress = r.Required
+	defer vault.DeleteFromStorage("requests/" + hash)
+
+	now := time.Now().UTC()
+	a := &SecretAccess{
+		RequestID:     hash,
+		Path:          r.Path,
+		Justification: r.Justification,
+		Requester:     r.Requester,
+		RequesterHash: r.RequesterHash,
+		RequesterID:   r.RequesterID,
+		Approved:      now.Format(time.RFC3339),
+		Expires:       now.Add(secretAccessClaimWindow).Format(time.RFC3339),
+	}
+	return a.save()
+}
+
+// purges the request entry and unseal tokens from goldfish's storage
+func (r *SecretAccessRequest) Reject(auth *vault.AuthInfo, hash string) error {
+	if err := vault.DeleteFromStorage("unseal_wrapping_tokens/" + hash); err != nil {
+		return err
+	}
+	if err := vault.DeleteFromStorage("requests/" + hash); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *SecretAccess) save() error {
+	return vault.WriteToStorage("secret_access/"+a.RequestID, structs.Map(a))
+}
+
+func loadSecretAccess(hash string) (*SecretAccess, error) {
+	data, err := vault.ReadFromStorage("secret_access/" + hash)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, errors.New("Secret access not found")
+	}
+
+	var a SecretAccess
+	if err := mapstructure.Decode(data, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// reads the approved secret with goldfish's token and returns it response-wrapped
+// this can only happen once, and only by the requester
+func ClaimSecretAccess(auth *vault.AuthInfo, hash string) (string, error) {
+	lockMap.Lock()
+	defer lockMap.Unlock()
+
+	a, err := loadSecretAccess(hash)
+	if err != nil {
+		return "", err
+	}
+
+	// only the requester can claim the secret
+	self, err := auth.LookupSelf()
+	if err != nil {
+		return "", err
+	}
+	if self == nil {
+		return "", errors.New("Could not confirm requester identity")
+	}
+	id, err := requesterID(self.Data)
+	if err != nil {
+		return "", err
+	}
+	if a.RequesterID == "" || id != a.RequesterID {
+		return "", errors.New("Only the requester can claim this secret")
+	}
+
+	if a.Claimed != "" {
+		return "", errors.New("Secret has already been claimed")
+	}
+	if expires, err := time.Parse(time.RFC3339, a.Expires); err != nil || time.Now().After(expires) {
+		return "", errors.New("Approval has lapsed, please submit a new request")
+	}
+
+	data, err := vault.ReadSecretAsGoldfish(a.Path)
+	if err != nil {
+		return "", err
+	}
+
+	wrapTTL := vault.GetConfig().SecretAccessWrapTTL
+	if wrapTTL == "" {
+		wrapTTL = defaultSecretAccessWrapTTL
+	}
+	wrapInfo, err := vault.WrapDataInfo(wrapTTL, data)
+	if err != nil {
+		return "", err
+	}
+
+	// the record must be marked as claimed, otherwise the secret could be read again
+	a.Claimed = time.Now().UTC().Format(time.RFC3339)
+	a.WrappedAccessor = wrapInfo.Accessor
+	if err := a.save(); err != nil {
+		return "", err
+	}
+	log.Println("[INFO ]: Secret", a.Path, "read on behalf of", a.Requester, "under request", a.RequestID)
+
+	return wrapInfo.Token, nil
+}
+
+// lists the access records of secrets that the caller can read
+func ListSecretAccesses(auth *vault.AuthInfo) ([]SecretAccess, error) {
+	hashes, err := vault.GetStorage().List("secret_access/")
+	if err != nil {
+		return nil, err
+	}
+
+	results := []SecretAccess{}
+	for _, hash := range hashes {
+		a, err := loadSecretAccess(hash)
+		if err != nil {
+			continue
+		}
+
+		// a requester should not learn about other sensitive paths through the audit trail
+		capabilities, err := auth.SecretCapabilities(a.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !hasCapability(capabilities, "read") {
+			continue
+		}
+
+		results = append(results, *a)
+	}
+	return results, nil
+}
+
+func hasCapability(capabilities []string, want string) bool {
+	for _, c := range capabilities {
+		if c == want || c == "root" {
+			return true
+		}
+	}
+	return false
+}
@@ -104,20 +104,70 @@ func StartListener(listener config.ListenerConfig, assets *rice.Box) {
 
 	e.GET("/v1/token/accessors", handlers.GetTokenAccessors())
 	e.POST("/v1/token/lookup-accessor", handlers.LookupTokenByAccessor())
+	e.GET("/v1/token/search", handlers.SearchTokens())
 	e.POST("/v1/token/revoke-accessor", handlers.RevokeTokenByAccessor())
+	e.POST("/v1/token/bulk-revoke", handlers.BulkRevokeTokens())
+	e.POST("/v1/token/revoke-subtree", handlers.RevokeTokenSubtree())
 	e.POST("/v1/token/revoke-self", handlers.RevokeSelf())
 	e.POST("/v1/token/create", handlers.CreateToken())
 	e.GET("/v1/token/listroles", handlers.ListRoles())
 	e.GET("/v1/token/role", handlers.GetRole())
+	e.POST("/v1/token/role", handlers.PostRole())
+	e.DELETE("/v1/token/role", handlers.DeleteRole())
+
+	e.GET("/v1/leases", handlers.GetLeases())
+	e.POST("/v1/leases/renew", handlers.RenewLease())
+	e.POST("/v1/leases/revoke", handlers.RevokeLease())
+	e.POST("/v1/leases/revoke-prefix", handlers.RevokeLeasePrefix())
 
 	e.GET("/v1/userpass/users", handlers.GetUserpassUsers())
+	e.GET("/v1/userpass/user", handlers.GetUserpassUser())
+	e.POST("/v1/userpass/create", handlers.CreateUserpassUser())
+	e.POST("/v1/userpass/update", handlers.UpdateUserpassUser())
+	e.POST("/v1/userpass/reset-password", handlers.ResetUserpassPassword())
+	e.POST("/v1/userpass/change-password", handlers.ChangeOwnPassword())
 	e.POST("/v1/userpass/delete", handlers.DeleteUserpassUser())
 
 	e.GET("/v1/approle/roles", handlers.GetApproleRoles())
+	e.POST("/v1/approle/role", handlers.PostApproleRole())
+	e.GET("/v1/approle/role-id", handlers.GetApproleRoleID())
+	e.GET("/v1/approle/secret-id-accessors", handlers.GetSecretIDAccessors())
+	e.POST("/v1/approle/secret-id-accessor/destroy", handlers.DestroySecretIDAccessor())
+	e.POST("/v1/approle/secret-id", handlers.IssueSecretID())
 	e.POST("/v1/approle/delete", handlers.DeleteApproleRole())
 
 	e.GET("/v1/ldap/groups", handlers.GetLDAPGroups())
 	e.GET("/v1/ldap/users", handlers.GetLDAPUsers())
+	e.POST("/v1/ldap/group", handlers.PostLDAPGroup())
+	e.POST("/v1/ldap/user", handlers.PostLDAPUser())
+	e.DELETE("/v1/ldap/group", handlers.DeleteLDAPGroup())
+	e.DELETE("/v1/ldap/user", handlers.DeleteLDAPUser())
+	e.GET("/v1/ldap/config", handlers.GetLDAPConfig())
+
+	e.GET("/v1/github/teams", handlers.GetAuthMappings("github", "teams"))
+	e.POST("/v1/github/teams", handlers.PostAuthMapping("github", "teams"))
+	e.DELETE("/v1/github/teams", handlers.DeleteAuthMapping("github", "teams"))
+	e.GET("/v1/github/users", handlers.GetAuthMappings("github", "users"))
+	e.POST("/v1/github/users", handlers.PostAuthMapping("github", "users"))
+	e.DELETE("/v1/github/users", handlers.DeleteAuthMapping("github", "users"))
+
+	e.GET("/v1/okta/groups", handlers.GetAuthMappings("okta", "groups"))
+	e.POST("/v1/okta/groups", handlers.PostAuthMapping("okta", "groups"))
+	e.DELETE("/v1/okta/groups", handlers.DeleteAuthMapping("okta", "groups"))
+	e.GET("/v1/okta/users", handlers.GetAuthMappings("okta", "users"))
+	e.POST("/v1/okta/users", handlers.PostAuthMapping("okta", "users"))
+	e.DELETE("/v1/okta/users", handlers.DeleteAuthMapping("okta", "users"))
+
+	e.GET("/v1/radius/users", handlers.GetAuthMappings("radius", "users"))
+	e.POST("/v1/radius/users", handlers.PostAuthMapping("radius", "users"))
+	e.DELETE("/v1/radius/users", handlers.DeleteAuthMapping("radius", "users"))
+
+	e.GET("/v1/cert/certs", handlers.GetTrustedCerts())
+	e.POST("/v1/cert/cert", handlers.PostTrustedCert())
+	e.DELETE("/v1/cert/cert", handlers.DeleteTrustedCert())
+
+	e.GET("/v1/audit", handlers.GetAuditDevices())
+	e.POST("/v1/audit/log", handlers.SearchAuditLog())
 
 	e.GET("/v1/policy", handlers.GetPolicy())
 	e.DELETE("/v1/policy", handlers.DeletePolicy())
@@ -128,16 +178,32 @@ func StartListener(listener config.ListenerConfig, assets *rice.Box) {
 	e.POST("/v1/request/approve", handlers.ApproveRequest())
 	e.DELETE("/v1/request/reject", handlers.RejectRequest())
 
+	e.GET("/v1/elevation", handlers.GetElevations())
+	e.POST("/v1/elevation/claim", handlers.ClaimElevation())
+	e.DELETE("/v1/elevation", handlers.RevokeElevation())
+
+	e.GET("/v1/secretaccess", handlers.GetSecretAccesses())
+	e.POST("/v1/secretaccess/claim", handlers.ClaimSecretAccess())
+
 	e.GET("/v1/transit", handlers.TransitInfo())
 	e.POST("/v1/transit/encrypt", handlers.EncryptString())
 	e.POST("/v1/transit/decrypt", handlers.DecryptString())
 
 	e.GET("/v1/mount", handlers.GetMount())
 	e.POST("/v1/mount", handlers.ConfigMount())
+	e.POST("/v1/mount/enable", handlers.EnableMount())
+	e.POST("/v1/mount/disable", handlers.DisableMount())
+	e.POST("/v1/mount/remount", handlers.Remount())
 
 	e.GET("/v1/secrets", handlers.GetSecrets())
+	e.GET("/v1/secrets/export", handlers.ExportSecrets())
+	e.POST("/v1/secrets/import", handlers.ImportSecrets())
+	e.POST("/v1/secrets/copy", handlers.CopySecrets())
+	e.GET("/v1/secrets/diff", handlers.DiffSecrets())
 	e.POST("/v1/secrets", handlers.PostSecrets())
 	e.DELETE("/v1/secrets", handlers.DeleteSecrets())
+	e.POST("/v1/secrets/undelete", handlers.UndeleteSecrets())
+	e.POST("/v1/secrets/destroy", handlers.DestroySecrets())
 
 	e.GET("/v1/bulletins", handlers.GetBulletins())
 
@@ -172,6 +238,11 @@ func StartListener(listener config.ListenerConfig, assets *rice.Box) {
 		tls.TLS_RSA_WITH_AES_256_CBC_SHA,
 	}
 
+	// browsers are asked for a client certificate, but goldfish verifies it only when logging in with it
+	if listener.Tls_client_auth {
+		e.TLSServer.TLSConfig.ClientAuth = tls.RequestClientCert
+	}
+
 	// if loading certificate from local machine
 	if listener.Cert != nil {
 		c, err := tls.LoadX509KeyPair(listener.Cert.Cert_file, listener.Cert.Key_file)
@@ -3,12 +3,19 @@ package slack
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"time"
 )
 
 var icon_url = "https://github.com/Caiyeon/goldfish/raw/master/frontend/client/assets/logo_small.png"
 
+var client = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
 func PostMessageWebhook(channel, main_text, attachment_text, webhook string) (err error) {
 	payload, err := json.Marshal(
 		map[string]interface{}{
@@ -27,8 +34,20 @@ func PostMessageWebhook(channel, main_text, attachment_text, webhook string) (er
 			},
 		},
 	)
-	if err == nil {
-		_, err = http.Post(webhook, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	// slack replies with 200 on success, anything else means the message was dropped
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("Slack responded with status code %d", resp.StatusCode)
 	}
 	return
 }
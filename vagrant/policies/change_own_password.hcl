@@ -0,0 +1,14 @@
+# this policy lets userpass users change their own password
+# in the "Change password" endpoint (POST /v1/userpass/change-password)
+
+# goldfish re-verifies the current password, then writes the new one with the user's own token.
+# the path is templated with the user's name, so users can only change their own password.
+# templating needs vault 0.11 or newer. Replace the accessor below with your userpass mount's
+# accessor, from `vault auth list` (e.g. auth_userpass_1a2b3c4d)
+path "auth/userpass/users/{{identity.entity.aliases.auth_userpass_1a2b3c4d.name}}/password" {
+  capabilities = ["update"]
+}
+
+# password rules can be set with PasswordPolicy in the run-time config, e.g.
+# PasswordPolicy = {"min_length": 12, "min_classes": 3, "banned": ["password", "goldfish"]}
+# without it, passwords must be at least 8 characters long
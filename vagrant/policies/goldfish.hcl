@@ -16,8 +16,36 @@ path "transit/decrypt/goldfish" {
 }
 
 
+# [optional]
+# for users without access to sys/auth to change their own userpass password
+path "sys/auth" {
+  capabilities = ["read"]
+}
+
+
 # [optional]
 # for goldfish to fetch certificates from PKI backend
 path "pki/issue/goldfish" {
   capabilities = ["update"]
 }
+
+
+# [optional]
+# for client certificate logins, set CertLoginRole in run-time config
+# the role's allowed_policies bound what certificate users can be given
+path "auth/cert/certs*" {
+  capabilities = ["read", "list"]
+}
+path "auth/cert/crls/*" {
+  capabilities = ["read"]
+}
+path "auth/token/create/goldfish-cert" {
+  capabilities = ["update"]
+}
+
+
+# [optional]
+# to store requests outside of goldfish's cubbyhole, set StoragePath in run-time config
+path "secret/goldfish-storage/*" {
+  capabilities = ["create", "read", "update", "delete", "list"]
+}
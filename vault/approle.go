@@ -3,6 +3,10 @@ package vault
 import (
 	"encoding/json"
 	"errors"
+	"net"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
 )
 
 type Role struct {
@@ -17,7 +21,24 @@ type Role struct {
 	Bound_cidr_list    string
 }
 
-func (auth AuthInfo) ListApproleRoles() ([]Role, error) {
+// details of an issued secret id. The secret id itself is never returned
+type SecretIDAccessor struct {
+	Accessor        string                 `json:"accessor"`
+	CreationTime    string                 `json:"creation_time"`
+	ExpirationTime  string                 `json:"expiration_time"`
+	SecretIDNumUses int64                  `json:"secret_id_num_uses"`
+	CIDRList        []interface{}          `json:"cidr_list"`
+	Metadata        map[string]interface{} `json:"metadata"`
+	Error           string                 `json:"error,omitempty"`
+}
+
+// lists roles in an approle backend. An empty mount means "approle"
+func (auth AuthInfo) ListApproleRoles(mount string) ([]Role, error) {
+	path, err := authMountPath(mount, "approle")
+	if err != nil {
+		return nil, err
+	}
+
 	client, err := auth.Client()
 	if err != nil {
 		return nil, err
@@ -25,7 +46,7 @@ func (auth AuthInfo) ListApproleRoles() ([]Role, error) {
 	logical := client.Logical()
 
 	// get a list of roles
-	resp, err := logical.List("auth/approle/role")
+	resp, err := logical.List(path + "/role")
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +64,7 @@ func (auth AuthInfo) ListApproleRoles() ([]Role, error) {
 	roles := make([]Role, len(rolenames))
 	for i, role := range rolenames {
 		roles[i].Roleid = role.(string)
-		resp, err := logical.Read("auth/approle/role/" + roles[i].Roleid)
+		resp, err := logical.Read(path + "/role/" + roles[i].Roleid)
 		if err == nil {
 			if b, err := json.Marshal(resp.Data); err == nil {
 				json.Unmarshal(b, &roles[i])
@@ -52,3 +73,213 @@ func (auth AuthInfo) ListApproleRoles() ([]Role, error) {
 	}
 	return roles, nil
 }
+
+func approleRolePath(mount, name string) (string, error) {
+	path, err := authMountPath(mount, "approle")
+	if err != nil {
+		return "", err
+	}
+	if err := validateTokenRoleName(name); err != nil {
+		return "", err
+	}
+	return path + "/role/" + name, nil
+}
+
+// checks a comma separated list of CIDR blocks
+func validateCIDRList(list string) error {
+	for _, cidr := range strings.Split(list, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return errors.New("Invalid CIDR block '" + cidr + "'")
+		}
+	}
+	return nil
+}
+
+// creates or replaces a role. r.Roleid is the role's name
+func (auth AuthInfo) WriteApproleRole(mount string, r Role) error {
+	path, err := approleRolePath(mount, r.Roleid)
+	if err != nil {
+		return err
+	}
+
+	// vault refuses roles that nothing is bound to
+	if !r.Bind_secret_id && strings.TrimSpace(r.Bound_cidr_list) == "" {
+		return errors.New("A role must bind a secret id, CIDR blocks, or both")
+	}
+	if err := validateCIDRList(r.Bound_cidr_list); err != nil {
+		return err
+	}
+	for _, n := range []int{r.Token_TTL, r.Token_max_TTL, r.Secret_id_TTL, r.Secret_id_num_uses, r.Period} {
+		if n < 0 {
+			return errors.New("TTLs, periods and use counts must not be negative")
+		}
+	}
+	if r.Token_max_TTL > 0 && r.Token_TTL > r.Token_max_TTL {
+		return errors.New("Token TTL must not be greater than token max TTL")
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Write(path, map[string]interface{}{
+		"token_ttl":          r.Token_TTL,
+		"token_max_ttl":      r.Token_max_TTL,
+		"secret_id_ttl":      r.Secret_id_TTL,
+		"secret_id_num_uses": r.Secret_id_num_uses,
+		"policies":           strings.Join(sortedPolicies(r.Policies), ","),
+		"period":             r.Period,
+		"bind_secret_id":     r.Bind_secret_id,
+		"bound_cidr_list":    r.Bound_cidr_list,
+	})
+	return err
+}
+
+func (auth AuthInfo) DeleteApproleRole(mount, name string) error {
+	path, err := approleRolePath(mount, name)
+	if err != nil {
+		return err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Delete(path)
+	return err
+}
+
+func (auth AuthInfo) GetApproleRoleID(mount, name string) (string, error) {
+	path, err := approleRolePath(mount, name)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Logical().Read(path + "/role-id")
+	if err != nil {
+		return "", err
+	}
+	if resp == nil {
+		return "", errors.New("Role not found")
+	}
+	roleID, _ := resp.Data["role_id"].(string)
+	return roleID, nil
+}
+
+// lists a role's secret id accessors, with each one's details
+func (auth AuthInfo) ListSecretIDAccessors(mount, name string) ([]SecretIDAccessor, error) {
+	path, err := approleRolePath(mount, name)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Logical().List(path + "/secret-id")
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Data == nil {
+		return []SecretIDAccessor{}, nil
+	}
+
+	raw, _ := resp.Data["keys"].([]interface{})
+	accessors := make([]SecretIDAccessor, 0, len(raw))
+	for _, a := range raw {
+		s, ok := a.(string)
+		if !ok {
+			continue
+		}
+		accessor := SecretIDAccessor{Accessor: s}
+		resp, err := client.Logical().Write(path+"/secret-id-accessor/lookup", map[string]interface{}{
+			"secret_id_accessor": s,
+		})
+		switch {
+		case err != nil:
+			accessor.Error = err.Error()
+		case resp != nil && resp.Data != nil:
+			accessor.CreationTime = tokenString(resp.Data, "creation_time")
+			accessor.ExpirationTime = tokenString(resp.Data, "expiration_time")
+			accessor.SecretIDNumUses = tokenInt(resp.Data, "secret_id_num_uses")
+			accessor.CIDRList, _ = resp.Data["cidr_list"].([]interface{})
+			accessor.Metadata, _ = resp.Data["metadata"].(map[string]interface{})
+		}
+		accessors = append(accessors, accessor)
+	}
+	return accessors, nil
+}
+
+func (auth AuthInfo) DestroySecretIDAccessor(mount, name, accessor string) error {
+	path, err := approleRolePath(mount, name)
+	if err != nil {
+		return err
+	}
+	if accessor == "" {
+		return errors.New("Empty secret id accessor")
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Write(path+"/secret-id-accessor/destroy", map[string]interface{}{
+		"secret_id_accessor": accessor,
+	})
+	return err
+}
+
+// issues a secret id for a role. It is always response-wrapped, so it only ever reaches
+// whoever unwraps it. cidrList optionally restricts where the secret id can be used from
+func (auth AuthInfo) IssueSecretID(mount, name, wrapTTL string, metadata map[string]string, cidrList string) (*api.SecretWrapInfo, error) {
+	path, err := approleRolePath(mount, name)
+	if err != nil {
+		return nil, err
+	}
+	if wrapTTL == "" {
+		return nil, errors.New("A wrap ttl is required")
+	}
+	if ttl, err := parseRoleDuration(wrapTTL); err != nil || ttl == 0 {
+		return nil, errors.New("Wrap ttl must be a positive duration, e.g. '15m'")
+	}
+	if err := validateCIDRList(cidrList); err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{}
+	if len(metadata) > 0 {
+		b, err := json.Marshal(metadata)
+		if err != nil {
+			return nil, err
+		}
+		data["metadata"] = string(b)
+	}
+	if cidrList != "" {
+		data["cidr_list"] = cidrList
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+	client.SetWrappingLookupFunc(func(operation, path string) string {
+		return wrapTTL
+	})
+
+	resp, err := client.Logical().Write(path+"/secret-id", data)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.WrapInfo == nil {
+		return nil, errors.New("Vault did not return a wrapping token")
+	}
+	return resp.WrapInfo, nil
+}
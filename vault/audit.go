@@ -0,0 +1,72 @@
+package vault
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// the audit device types that vault ships with, and the options each one needs
+var auditDeviceRequiredOptions = map[string][]string{
+	"file":   {"file_path"},
+	"syslog": {},
+	"socket": {"address"},
+}
+
+// returns enabled audit devices keyed by path, if authorized
+func (auth AuthInfo) ListAuditDevices() (map[string]*api.Audit, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Sys().ListAudit()
+}
+
+// checks that an audit device could be enabled with these settings
+func ValidateAuditDevice(path, deviceType string, options map[string]string) error {
+	if strings.Trim(path, "/") == "" {
+		return errors.New("Empty audit device path")
+	}
+	required, ok := auditDeviceRequiredOptions[deviceType]
+	if !ok {
+		return errors.New("Audit device type must be file, syslog or socket")
+	}
+	for _, opt := range required {
+		if options[opt] == "" {
+			return errors.New("Audit device type " + deviceType + " requires option '" + opt + "'")
+		}
+	}
+	return nil
+}
+
+func (auth AuthInfo) EnableAuditDevice(path, deviceType, description string, options map[string]string) error {
+	if err := ValidateAuditDevice(path, deviceType, options); err != nil {
+		return err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+
+	return client.Sys().EnableAuditWithOptions(strings.Trim(path, "/"), &api.EnableAuditOptions{
+		Type:        deviceType,
+		Description: description,
+		Options:     options,
+	})
+}
+
+func (auth AuthInfo) DisableAuditDevice(path string) error {
+	if strings.Trim(path, "/") == "" {
+		return errors.New("Empty audit device path")
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+
+	return client.Sys().DisableAudit(strings.Trim(path, "/"))
+}
@@ -0,0 +1,178 @@
+package vault
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// audit entries can carry large request and response bodies
+	maxAuditLineSize = 16 * 1024 * 1024
+	// upper bound of entries returned by a single search
+	maxAuditSearchResults = 1000
+)
+
+// one line of a vault file audit log. Request and response bodies are kept as is
+type AuditLogEntry struct {
+	Time  string `json:"time"`
+	Type  string `json:"type"`
+	Error string `json:"error"`
+	Auth  struct {
+		Accessor    string   `json:"accessor"`
+		DisplayName string   `json:"display_name"`
+		Policies    []string `json:"policies"`
+	} `json:"auth"`
+	Request struct {
+		ID            string                 `json:"id"`
+		Operation     string                 `json:"operation"`
+		Path          string                 `json:"path"`
+		RemoteAddress string                 `json:"remote_address"`
+		Data          map[string]interface{} `json:"data"`
+	} `json:"request"`
+	Response map[string]interface{} `json:"response,omitempty"`
+}
+
+// narrows down an audit log search. Zero values are ignored
+type AuditLogFilter struct {
+	Since time.Time
+	Until time.Time
+	// prefix
+	Path      string
+	Operation string
+	// substring
+	DisplayName   string
+	RemoteAddress string
+	// an hmac'd value as it appears in the log, e.g. "hmac-sha256:..."
+	Hash  string
+	Limit int
+}
+
+// audit hashes are only meaningful to the device that produced them,
+// so the device that writes the configured log is found by its file path
+func (auth AuthInfo) auditLogDevice() (string, error) {
+	devices, err := auth.ListAuditDevices()
+	if err != nil {
+		return "", err
+	}
+	for path, d := range devices {
+		if d.Type == "file" && d.Options["file_path"] == vaultConfig.Audit_log {
+			return strings.TrimSuffix(path, "/"), nil
+		}
+	}
+	return "", errors.New("No file audit device writes to " + vaultConfig.Audit_log)
+}
+
+// hashes a plaintext value the way the audit log's device would
+func (auth AuthInfo) AuditHash(input string) (string, error) {
+	if input == "" {
+		return "", errors.New("Empty value")
+	}
+	device, err := auth.auditLogDevice()
+	if err != nil {
+		return "", err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return "", err
+	}
+	return client.Sys().AuditHash(device, input)
+}
+
+// returns the newest entries in the configured audit log that match the filter, newest first
+// the caller must be able to list audit devices, which requires sudo
+func (auth AuthInfo) SearchAuditLog(f AuditLogFilter) ([]AuditLogEntry, error) {
+	if vaultConfig.Audit_log == "" {
+		return nil, errors.New("No audit log is configured")
+	}
+	if f.Limit <= 0 {
+		f.Limit = 100
+	}
+	if f.Limit > maxAuditSearchResults {
+		f.Limit = maxAuditSearchResults
+	}
+
+	if _, err := auth.ListAuditDevices(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(vaultConfig.Audit_log)
+	if err != nil {
+		return nil, errors.New("Could not open audit log: " + err.Error())
+	}
+	defer file.Close()
+
+	// the log is oldest first, so only the last matches are kept
+	matches := make([]AuditLogEntry, 0, f.Limit)
+	next := 0
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxAuditLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		// cheap checks before decoding
+		if f.Hash != "" && !strings.Contains(string(line), f.Hash) {
+			continue
+		}
+		if f.Path != "" && !strings.Contains(string(line), f.Path) {
+			continue
+		}
+
+		var e AuditLogEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			continue
+		}
+		if !f.matches(e) {
+			continue
+		}
+
+		if len(matches) < f.Limit {
+			matches = append(matches, e)
+		} else {
+			matches[next] = e
+		}
+		next = (next + 1) % f.Limit
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New("Could not read audit log: " + err.Error())
+	}
+
+	// unroll the ring, newest first
+	result := make([]AuditLogEntry, 0, len(matches))
+	for i := 0; i < len(matches); i++ {
+		result = append(result, matches[(next-1-i+2*len(matches))%len(matches)])
+	}
+	return result, nil
+}
+
+func (f AuditLogFilter) matches(e AuditLogEntry) bool {
+	if !f.Since.IsZero() || !f.Until.IsZero() {
+		t, err := time.Parse(time.RFC3339Nano, e.Time)
+		if err != nil {
+			return false
+		}
+		if !f.Since.IsZero() && t.Before(f.Since) {
+			return false
+		}
+		if !f.Until.IsZero() && t.After(f.Until) {
+			return false
+		}
+	}
+	if f.Path != "" && !strings.HasPrefix(e.Request.Path, f.Path) {
+		return false
+	}
+	if f.Operation != "" && e.Request.Operation != f.Operation {
+		return false
+	}
+	if f.DisplayName != "" && !strings.Contains(e.Auth.DisplayName, f.DisplayName) {
+		return false
+	}
+	if f.RemoteAddress != "" && !strings.Contains(e.Request.RemoteAddress, f.RemoteAddress) {
+		return false
+	}
+	return true
+}
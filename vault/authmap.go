@@ -0,0 +1,158 @@
+package vault
+
+import (
+	"errors"
+	"strings"
+)
+
+// maps a team, group or user of an auth backend to vault policies
+// okta users can also be given extra groups
+type AuthMapping struct {
+	Name     string
+	Policies []string
+	Groups   []string `json:",omitempty"`
+}
+
+// where a kind of mapping lives in its backend, and how it is written
+type authMappingKind struct {
+	path string
+	// github keeps policies under 'value'
+	policiesKey string
+	groups      bool
+}
+
+// supported backends, and the kinds of mappings in each. Backend names are also their default mounts
+var authMappingKinds = map[string]map[string]authMappingKind{
+	"github": {
+		"teams": {path: "map/teams", policiesKey: "value"},
+		"users": {path: "map/users", policiesKey: "value"},
+	},
+	"okta": {
+		"groups": {path: "groups", policiesKey: "policies"},
+		"users":  {path: "users", policiesKey: "policies", groups: true},
+	},
+	// radius has no groups
+	"radius": {
+		"users": {path: "users", policiesKey: "policies"},
+	},
+}
+
+func lookupAuthMappingKind(backend, kind string) (authMappingKind, error) {
+	kinds, ok := authMappingKinds[backend]
+	if !ok {
+		return authMappingKind{}, errors.New("Unsupported auth backend: " + backend)
+	}
+	k, ok := kinds[kind]
+	if !ok {
+		return authMappingKind{}, errors.New("The " + backend + " backend has no " + kind)
+	}
+	return k, nil
+}
+
+// lists a backend's mappings of one kind. An empty mount means the backend's default
+func (auth AuthInfo) ListAuthMappings(backend, kind, mount string) ([]AuthMapping, error) {
+	k, err := lookupAuthMappingKind(backend, kind)
+	if err != nil {
+		return nil, err
+	}
+	path, err := authMountPath(mount, backend)
+	if err != nil {
+		return nil, err
+	}
+	path += "/" + k.path
+
+	names, err := auth.listNames(path)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]AuthMapping, len(names))
+	for i, name := range names {
+		results[i] = AuthMapping{
+			Name:     name,
+			Policies: []string{},
+		}
+		if k.groups {
+			results[i].Groups = []string{}
+		}
+
+		resp, err := client.Logical().Read(path + "/" + name)
+		if err != nil || resp == nil {
+			continue
+		}
+		results[i].Policies = parseStringList(resp.Data[k.policiesKey])
+		if k.groups {
+			results[i].Groups = parseStringList(resp.Data["groups"])
+		}
+	}
+
+	return results, nil
+}
+
+// creates or replaces a mapping
+func (auth AuthInfo) WriteAuthMapping(backend, kind, mount string, m AuthMapping) error {
+	k, err := lookupAuthMappingKind(backend, kind)
+	if err != nil {
+		return err
+	}
+	path, err := authMappingPath(backend, k, mount, m.Name)
+	if err != nil {
+		return err
+	}
+	if len(m.Groups) > 0 && !k.groups {
+		return errors.New("The " + backend + " backend's " + kind + " cannot be given groups")
+	}
+	for _, g := range m.Groups {
+		if strings.TrimSpace(g) == "" || strings.Contains(g, ",") {
+			return errors.New("Group names must not be empty or contain ','")
+		}
+	}
+
+	data := map[string]interface{}{
+		k.policiesKey: strings.Join(sortedPolicies(m.Policies), ","),
+	}
+	if k.groups {
+		data["groups"] = strings.Join(m.Groups, ",")
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Write(path, data)
+	return err
+}
+
+func (auth AuthInfo) DeleteAuthMapping(backend, kind, mount, name string) error {
+	k, err := lookupAuthMappingKind(backend, kind)
+	if err != nil {
+		return err
+	}
+	path, err := authMappingPath(backend, k, mount, name)
+	if err != nil {
+		return err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Delete(path)
+	return err
+}
+
+func authMappingPath(backend string, k authMappingKind, mount, name string) (string, error) {
+	path, err := authMountPath(mount, backend)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(name) == "" || strings.Contains(name, "/") {
+		return "", errors.New("Invalid name")
+	}
+	return path + "/" + k.path + "/" + name, nil
+}
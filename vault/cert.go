@@ -0,0 +1,372 @@
+package vault
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/ryanuber/go-glob"
+)
+
+// a certificate trusted by a cert auth backend, at auth/<mount>/certs/<name>
+type TrustedCert struct {
+	Name string `json:"name"`
+	// x509 PEM. A CA, or a client certificate allowed to log in directly
+	Certificate  string   `json:"certificate"`
+	DisplayName  string   `json:"display_name"`
+	AllowedNames []string `json:"allowed_names"`
+	Policies     []string `json:"policies"`
+	// seconds, or a duration string such as "72h"
+	TTL string `json:"ttl"`
+
+	// read from the certificate when listing
+	Subject  string `json:"subject,omitempty"`
+	NotAfter string `json:"not_after,omitempty"`
+	IsCA     bool   `json:"is_ca,omitempty"`
+}
+
+func trustedCertPath(mount, name string) (string, error) {
+	path, err := authMountPath(mount, "cert")
+	if err != nil {
+		return "", err
+	}
+	if err := validateTokenRoleName(name); err != nil {
+		return "", err
+	}
+	return path + "/certs/" + strings.ToLower(name), nil
+}
+
+// returns the first certificate in a PEM bundle
+func parseTrustedCert(raw string) (*x509.Certificate, error) {
+	rest := []byte(raw)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("Certificate must contain a PEM encoded certificate")
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.New("Could not parse certificate: " + err.Error())
+		}
+		return cert, nil
+	}
+}
+
+// lists a cert backend's trusted certificates. An empty mount means "cert"
+func (auth AuthInfo) ListTrustedCerts(mount string) ([]TrustedCert, error) {
+	path, err := authMountPath(mount, "cert")
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := auth.listNames(path + "/certs")
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TrustedCert, len(names))
+	for i, name := range names {
+		results[i] = TrustedCert{
+			Name:         name,
+			AllowedNames: []string{},
+			Policies:     []string{},
+		}
+
+		resp, err := client.Logical().Read(path + "/certs/" + name)
+		if err != nil || resp == nil {
+			continue
+		}
+		results[i].Certificate = tokenString(resp.Data, "certificate")
+		results[i].DisplayName = tokenString(resp.Data, "display_name")
+		results[i].Policies = parseStringList(resp.Data["policies"])
+		// older vault versions don't return allowed names
+		results[i].AllowedNames = parseStringList(resp.Data["allowed_names"])
+		results[i].TTL = strconv.FormatInt(tokenInt(resp.Data, "ttl"), 10)
+
+		if cert, err := parseTrustedCert(results[i].Certificate); err == nil {
+			results[i].Subject = cert.Subject.String()
+			results[i].NotAfter = cert.NotAfter.UTC().Format(time.RFC3339)
+			results[i].IsCA = cert.IsCA
+		}
+	}
+
+	return results, nil
+}
+
+// creates or replaces a trusted certificate
+func (auth AuthInfo) WriteTrustedCert(mount string, c TrustedCert) error {
+	path, err := trustedCertPath(mount, c.Name)
+	if err != nil {
+		return err
+	}
+
+	cert, err := parseTrustedCert(c.Certificate)
+	if err != nil {
+		return err
+	}
+	if time.Now().After(cert.NotAfter) {
+		return errors.New("Certificate expired on " + cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	for _, name := range c.AllowedNames {
+		if strings.TrimSpace(name) == "" || strings.Contains(name, ",") {
+			return errors.New("Allowed names must not be empty or contain ','")
+		}
+	}
+	ttl, err := parseRoleDuration(c.TTL)
+	if err != nil {
+		return errors.New("ttl: " + err.Error())
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Write(path, map[string]interface{}{
+		"certificate":   c.Certificate,
+		"display_name":  c.DisplayName,
+		"allowed_names": strings.Join(c.AllowedNames, ","),
+		"policies":      strings.Join(sortedPolicies(c.Policies), ","),
+		"ttl":           ttl,
+	})
+	return err
+}
+
+func (auth AuthInfo) DeleteTrustedCert(mount, name string) error {
+	path, err := trustedCertPath(mount, name)
+	if err != nil {
+		return err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Delete(path)
+	return err
+}
+
+// logs in a user who presented a client certificate to goldfish's listener
+// the certificate is matched against the trusted certificates of a cert backend, as vault itself would,
+// then goldfish creates a token for the user from CertLoginRole with the matching entry's policies
+// vault can't do this itself: its cert backend only sees goldfish's connection, not the user's
+func (auth *AuthInfo) CertLogin(peers []*x509.Certificate) (map[string]interface{}, error) {
+	conf := GetConfig()
+	if conf.CertLoginRole == "" {
+		return nil, errors.New("Certificate logins are not enabled")
+	}
+	if len(peers) == 0 {
+		return nil, errors.New("No client certificate was presented")
+	}
+
+	mount := auth.Path
+	if mount == "" {
+		mount = conf.CertLoginMount
+	}
+
+	// the user may not be able to read the trusted certificates, but goldfish must
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := revokedSerials(client, mount, conf.CertLoginCRLs)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := matchTrustedCert(client, mount, peers, revoked)
+	if err != nil {
+		return nil, err
+	}
+
+	// like vault's cert backend, an entry without policies only grants the default policy
+	policies := entry.Policies
+	if len(policies) == 0 {
+		policies = []string{"default"}
+	}
+
+	leaf := peers[0]
+	displayName := entry.DisplayName
+	if displayName == "" {
+		displayName = entry.Name
+	}
+	opts := &api.TokenCreateRequest{
+		Policies:    policies,
+		DisplayName: displayName,
+		Metadata: map[string]string{
+			"cert_name":      entry.Name,
+			"common_name":    leaf.Subject.CommonName,
+			"serial_number":  leaf.SerialNumber.String(),
+			"subject_key_id": fmt.Sprintf("%x", leaf.SubjectKeyId),
+		},
+	}
+	if entry.TTL != "" && entry.TTL != "0" {
+		opts.TTL = entry.TTL + "s"
+	}
+	resp, err := client.Auth().Token().CreateWithRole(opts, conf.CertLoginRole)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return nil, errors.New("Unable to parse vault response")
+	}
+
+	// from here on, the user is just another token
+	auth.Type = "token"
+	auth.ID = resp.Auth.ClientToken
+	auth.Pass = ""
+	self, err := auth.LookupSelf()
+	if err != nil {
+		return nil, err
+	}
+	if self == nil {
+		return nil, errors.New("Could not look up the created token")
+	}
+	return self.Data, nil
+}
+
+// reads the serials revoked by the named CRLs of a cert backend
+// a CRL that can't be read fails the login, rather than letting its revoked certificates through
+func revokedSerials(client *api.Client, mount, crls string) (map[string]bool, error) {
+	path, err := authMountPath(mount, "cert")
+	if err != nil {
+		return nil, err
+	}
+
+	revoked := make(map[string]bool)
+	for _, name := range strings.Split(crls, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		resp, err := client.Logical().Read(path + "/crls/" + name)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Data == nil {
+			return nil, errors.New("CRL " + name + " was not found in " + path)
+		}
+		serials, _ := resp.Data["serials"].(map[string]interface{})
+		for serial := range serials {
+			revoked[serial] = true
+		}
+	}
+	return revoked, nil
+}
+
+// like vault's cert backend, a chain is refused if any certificate in it has been revoked
+func chainRevoked(chain []*x509.Certificate, revoked map[string]bool) bool {
+	for _, c := range chain {
+		if revoked[c.SerialNumber.String()] {
+			return true
+		}
+	}
+	return false
+}
+
+// finds the cert backend entry that accepts a client's certificate chain
+// CA entries must verify the chain, and other entries must be the client's certificate itself
+func matchTrustedCert(client *api.Client, mount string, peers []*x509.Certificate,
+	revoked map[string]bool) (*TrustedCert, error) {
+	path, err := authMountPath(mount, "cert")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Logical().List(path + "/certs")
+	if err != nil {
+		return nil, err
+	}
+	var names []interface{}
+	if resp != nil && resp.Data != nil {
+		names, _ = resp.Data["keys"].([]interface{})
+	}
+
+	leaf := peers[0]
+	intermediates := x509.NewCertPool()
+	for _, c := range peers[1:] {
+		intermediates.AddCert(c)
+	}
+
+	for _, raw := range names {
+		name, _ := raw.(string)
+		resp, err := client.Logical().Read(path + "/certs/" + name)
+		if err != nil || resp == nil {
+			continue
+		}
+		entry := &TrustedCert{
+			Name:         name,
+			Certificate:  tokenString(resp.Data, "certificate"),
+			DisplayName:  tokenString(resp.Data, "display_name"),
+			AllowedNames: parseStringList(resp.Data["allowed_names"]),
+			Policies:     parseStringList(resp.Data["policies"]),
+			TTL:          strconv.FormatInt(tokenInt(resp.Data, "ttl"), 10),
+		}
+		trusted, err := parseTrustedCert(entry.Certificate)
+		if err != nil {
+			continue
+		}
+
+		// one chain without revoked certificates is enough, as in vault
+		valid := false
+		if trusted.IsCA {
+			roots := x509.NewCertPool()
+			roots.AddCert(trusted)
+			chains, err := leaf.Verify(x509.VerifyOptions{
+				Roots:         roots,
+				Intermediates: intermediates,
+				KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			})
+			if err != nil {
+				continue
+			}
+			for _, chain := range chains {
+				if !chainRevoked(chain, revoked) {
+					valid = true
+				}
+			}
+		} else {
+			now := time.Now()
+			if !trusted.Equal(leaf) || now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+				continue
+			}
+			valid = !chainRevoked([]*x509.Certificate{trusted}, revoked)
+		}
+
+		if valid && certNameAllowed(leaf, entry.AllowedNames) {
+			return entry, nil
+		}
+	}
+
+	return nil, errors.New("Certificate is not trusted by " + path)
+}
+
+// the same matching as vault's cert backend: no allowed names means any name,
+// otherwise a glob pattern must match the CN, a DNS name or an email
+func certNameAllowed(cert *x509.Certificate, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	names := append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, pattern := range allowed {
+		for _, name := range names {
+			if glob.Glob(pattern, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
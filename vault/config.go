@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -19,9 +20,51 @@ type RuntimeConfig struct {
 	DefaultSecretPath string
 	BulletinPath      string
 
+	// if set, requests are stored here instead of goldfish's cubbyhole. Must be on a kv v1 mount
+	// entries are encrypted with StorageTransitKey, or ServerTransitKey if empty
+	// existing entries are moved over whenever either of these change
+	StoragePath       string
+	StorageTransitKey string
+
+	// upper bound for privilege elevation requests, e.g. "8h". Defaults to 24h
+	MaxElevationTTL string
+
+	// json object of path prefixes to schemas that secret writes must satisfy. See SecretSchema
+	SecretSchemas string
+
+	// json password rules for self-service password changes. See PasswordPolicy
+	PasswordPolicy string
+
+	// how long a one-time secret read can be unwrapped for, e.g. "10m". Defaults to 5m
+	SecretAccessWrapTTL string
+
+	// client certificate logins: certificates trusted by CertLoginMount's cert backend (default "cert")
+	// are given a token from this token role, with the matching entry's policies. Empty disables it
+	// CertLoginCRLs is a comma delimited list of the backend's CRL names. Vault can't list them,
+	// so certificates are only checked against the CRLs named here
+	CertLoginRole  string
+	CertLoginMount string
+	CertLoginCRLs  string
+
 	SlackWebhook string
 	SlackChannel string
 
+	// generic json webhook, signed with HMAC-SHA256 if a secret is set
+	WebhookURL    string
+	WebhookSecret string
+
+	MattermostWebhook string
+	MattermostChannel string
+
+	TeamsWebhook string
+
+	// smtp email notifications. SMTPTo is comma delimited
+	SMTPAddress  string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	SMTPTo       string
+
 	GithubAccessToken  string
 	GithubRepoOwner    string
 	GithubRepo         string
@@ -35,6 +78,9 @@ var (
 	conf                       = RuntimeConfig{}
 	configLock                 = new(sync.RWMutex)
 	configHash          uint64 = 0
+	// parsed from conf.SecretSchemas and conf.PasswordPolicy, guarded by configLock
+	secretSchemas  []*SecretSchema
+	passwordPolicy = defaultPasswordPolicy
 )
 
 func GetConfig() RuntimeConfig {
@@ -43,6 +89,18 @@ func GetConfig() RuntimeConfig {
 	return conf
 }
 
+func getSecretSchemas() []*SecretSchema {
+	configLock.RLock()
+	defer configLock.RUnlock()
+	return secretSchemas
+}
+
+func getPasswordPolicy() PasswordPolicy {
+	configLock.RLock()
+	defer configLock.RUnlock()
+	return passwordPolicy
+}
+
 func loadConfigFromVault(path string) error {
 	client, err := NewGoldfishVaultClient()
 	if err != nil {
@@ -72,6 +130,44 @@ func loadConfigFromVault(path string) error {
 		temp.SlackChannel = ""
 	}
 
+	// other webhooks must at least be proper http(s) urls
+	if !isWebURL(temp.WebhookURL) {
+		temp.WebhookURL = ""
+		temp.WebhookSecret = ""
+	}
+	if !isWebURL(temp.MattermostWebhook) {
+		temp.MattermostWebhook = ""
+		temp.MattermostChannel = ""
+	}
+	if !isWebURL(temp.TeamsWebhook) {
+		temp.TeamsWebhook = ""
+	}
+
+	// fall back to the default wrap ttl rather than failing every secret claim
+	if _, err := time.ParseDuration(temp.SecretAccessWrapTTL); err != nil {
+		temp.SecretAccessWrapTTL = ""
+	}
+
+	// state stored outside of the cubbyhole must be encrypted
+	if temp.StoragePath != "" && temp.StorageTransitKey == "" && temp.ServerTransitKey == "" {
+		return errors.New("StoragePath requires StorageTransitKey or ServerTransitKey to be set")
+	}
+	// entries are written as flat kv v1 secrets
+	if temp.StoragePath != "" && kvMountFor(client, temp.StoragePath).Version == 2 {
+		return errors.New("StoragePath must be on a kv version 1 mount, " +
+			temp.StoragePath + " is on a kv version 2 mount")
+	}
+
+	// a broken schema should not silently stop validating writes
+	schemas, err := parseSecretSchemas(temp.SecretSchemas)
+	if err != nil {
+		return err
+	}
+	policy, err := parsePasswordPolicy(temp.PasswordPolicy)
+	if err != nil {
+		return err
+	}
+
 	// don't waste a lock if nothing has changed
 	newHash, err := hashstructure.Hash(temp, nil)
 	if err != nil {
@@ -81,21 +177,43 @@ func loadConfigFromVault(path string) error {
 		return nil
 	}
 
+	// if goldfish's state is moving to another backend, bring existing entries along
+	// requests are held off until the new config is in place, so none are written to the old backend
+	StorageLock.Lock()
+	defer StorageLock.Unlock()
+	migration, err := copyStorage(GetStorage(), storageFromConfig(temp))
+	if err != nil {
+		return errors.New("Goldfish could not migrate storage to the new storage path: " + err.Error())
+	}
+
 	// timestamp the change in vault, notifying operators that the config has been updated
 	// if timestamp can't be written, operation should be aborted
 	temp.LastUpdated = time.Now().Format(time.UnixDate)
 	_, err = client.Logical().Write(path, structs.Map(temp))
 	if err != nil {
+		migration.rollback()
 		return errors.New("Goldfish could not write to runtime config path: " + err.Error())
 	}
 
 	// RWLock.Lock() will block read lock requests until it is done
 	configLock.Lock()
-	defer configLock.Unlock()
-
 	conf = temp
 	configHash = newHash
+	secretSchemas = schemas
+	passwordPolicy = policy
+	configLock.Unlock()
+
+	// the old backend is only cleaned up once the new one is in use
+	migration.finish()
+	if len(migration.names) > 0 {
+		log.Println("[INFO ]: Migrated", len(migration.names), "storage entries")
+	}
 
 	log.Println("[INFO ]: Server config reloaded")
 	return nil
 }
+
+func isWebURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,160 @@
+package vault
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// what to do when a destination secret already exists
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictFail      = "fail"
+)
+
+// the outcome (or planned outcome) of copying one secret
+// Action is one of "create", "overwrite", "skip", "conflict" or "error"
+type CopyResult struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Action      string `json:"action"`
+	Moved       bool   `json:"moved,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// lists what copying src to dest would do, without writing anything
+// src can be a single secret, or a subtree if it ends in '/'
+func (auth AuthInfo) PlanCopy(src, dest, conflict string) ([]CopyResult, error) {
+	plan, _, err := auth.planCopy(src, dest, conflict)
+	return plan, err
+}
+
+// copies a secret or subtree to dest, which may be on a different mount or kv version
+// if move is set, each source secret is deleted once it has been written to dest
+func (auth AuthInfo) CopySecrets(src, dest, conflict string, move bool) ([]CopyResult, error) {
+	plan, data, err := auth.planCopy(src, dest, conflict)
+	if err != nil {
+		return nil, err
+	}
+
+	// nothing is written if any destination conflicts and the caller asked to fail
+	conflicts := 0
+	for _, r := range plan {
+		if r.Action == "conflict" {
+			conflicts++
+		}
+	}
+	if conflicts > 0 {
+		return plan, errors.New(strconv.Itoa(conflicts) + " destination secret(s) already exist")
+	}
+
+	for i, r := range plan {
+		if r.Action != "create" && r.Action != "overwrite" {
+			continue
+		}
+		if _, err := auth.WriteSecretData(r.Destination, data[r.Source]); err != nil {
+			plan[i].Action = "error"
+			plan[i].Error = err.Error()
+			continue
+		}
+		if move {
+			// a soft delete would leave the source's versions behind on kv v2
+			if _, err := auth.RemoveSecret(r.Source); err != nil {
+				plan[i].Error = "Copied, but source could not be deleted: " + err.Error()
+				continue
+			}
+			plan[i].Moved = true
+		}
+	}
+	return plan, nil
+}
+
+func (auth AuthInfo) planCopy(src, dest, conflict string) ([]CopyResult, map[string]map[string]interface{}, error) {
+	src = strings.TrimPrefix(src, "/")
+	dest = strings.TrimPrefix(dest, "/")
+	if src == "" || dest == "" {
+		return nil, nil, errors.New("Source and destination must not be empty")
+	}
+	switch conflict {
+	case "":
+		conflict = ConflictFail
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+	default:
+		return nil, nil, errors.New("Conflict must be one of 'skip', 'overwrite' or 'fail'")
+	}
+
+	plan := []CopyResult{}
+	data := make(map[string]map[string]interface{})
+
+	if strings.HasSuffix(src, "/") {
+		// subtrees are copied under dest, keeping their relative layout
+		if !strings.HasSuffix(dest, "/") {
+			dest += "/"
+		}
+		if strings.HasPrefix(dest, src) {
+			return nil, nil, errors.New("Destination must not be inside the source")
+		}
+
+		export, skipped, err := auth.ExportSecrets(src)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, path := range skipped {
+			plan = append(plan, CopyResult{
+				Source: path,
+				Action: "error",
+				Error:  "Could not be read",
+			})
+		}
+		for _, rel := range export.paths() {
+			data[src+rel] = export.Secrets[rel]
+			plan = append(plan, CopyResult{
+				Source:      src + rel,
+				Destination: dest + rel,
+			})
+		}
+	} else {
+		// a single secret copied into a folder keeps its name
+		if strings.HasSuffix(dest, "/") {
+			dest += src[strings.LastIndex(src, "/")+1:]
+		}
+		if dest == src {
+			return nil, nil, errors.New("Source and destination must be different")
+		}
+
+		secret, err := auth.ReadSecret(src)
+		if err != nil {
+			return nil, nil, err
+		}
+		data[src] = secret
+		plan = append(plan, CopyResult{
+			Source:      src,
+			Destination: dest,
+		})
+	}
+
+	for i, r := range plan {
+		if r.Action != "" {
+			continue
+		}
+		exists, err := auth.SecretExists(r.Destination)
+		if err != nil {
+			plan[i].Action = "error"
+			plan[i].Error = err.Error()
+			continue
+		}
+		switch {
+		case !exists:
+			plan[i].Action = "create"
+		case conflict == ConflictOverwrite:
+			plan[i].Action = "overwrite"
+		case conflict == ConflictSkip:
+			plan[i].Action = "skip"
+		default:
+			plan[i].Action = "conflict"
+		}
+	}
+
+	return plan, data, nil
+}
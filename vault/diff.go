@@ -0,0 +1,143 @@
+package vault
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// shown in place of secret values, unless the caller asks to reveal them
+const maskedValue = "********"
+
+// the differences between two versions of a secret
+// Status is "added" or "removed" if the secret only exists on one side, otherwise "changed"
+type SecretDiff struct {
+	Path    string                 `json:"path"`
+	From    string                 `json:"from"`
+	To      string                 `json:"to"`
+	Status  string                 `json:"status"`
+	Added   map[string]interface{} `json:"added"`
+	Removed map[string]interface{} `json:"removed"`
+	Changed map[string]ValueChange `json:"changed"`
+}
+
+type ValueChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// compares two secrets, or two subtrees if both paths end in '/'
+// only secrets that differ are returned. Values are masked unless reveal is set
+// paths that could not be read are returned separately
+func (auth AuthInfo) DiffSecrets(from, to string, reveal bool) ([]SecretDiff, []string, error) {
+	from = strings.TrimPrefix(from, "/")
+	to = strings.TrimPrefix(to, "/")
+	if from == "" || to == "" {
+		return nil, nil, errors.New("Both paths must not be empty")
+	}
+	if strings.HasSuffix(from, "/") != strings.HasSuffix(to, "/") {
+		return nil, nil, errors.New("Paths must both be secrets, or both be directories ending in '/'")
+	}
+
+	// single secrets
+	if !strings.HasSuffix(from, "/") {
+		a, err := auth.ReadSecret(from)
+		if err != nil {
+			return nil, nil, err
+		}
+		b, err := auth.ReadSecret(to)
+		if err != nil {
+			return nil, nil, err
+		}
+		diffs := []SecretDiff{}
+		if d := diffSecret(a, b, reveal); d != nil {
+			d.From, d.To = from, to
+			diffs = append(diffs, *d)
+		}
+		return diffs, []string{}, nil
+	}
+
+	// subtrees are matched up by their relative paths
+	a, skippedA, err := auth.ExportSecrets(from)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, skippedB, err := auth.ExportSecrets(to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := a.paths()
+	for _, rel := range b.paths() {
+		if _, ok := a.Secrets[rel]; !ok {
+			paths = append(paths, rel)
+		}
+	}
+	sort.Strings(paths)
+
+	// an unreadable path is only reported as skipped, not as missing from one side
+	skipped := append(skippedA, skippedB...)
+	unreadable := func(path string) bool {
+		for _, s := range skipped {
+			if path == s || (strings.HasSuffix(s, "/") && strings.HasPrefix(path, s)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	diffs := []SecretDiff{}
+	for _, rel := range paths {
+		if unreadable(from+rel) || unreadable(to+rel) {
+			continue
+		}
+		if d := diffSecret(a.Secrets[rel], b.Secrets[rel], reveal); d != nil {
+			d.Path, d.From, d.To = rel, from+rel, to+rel
+			diffs = append(diffs, *d)
+		}
+	}
+	return diffs, skipped, nil
+}
+
+// a nil secret means it does not exist on that side. Returns nil if there are no differences
+func diffSecret(a, b map[string]interface{}, reveal bool) *SecretDiff {
+	mask := func(v interface{}) interface{} {
+		if reveal {
+			return v
+		}
+		return maskedValue
+	}
+
+	d := &SecretDiff{
+		Status:  "changed",
+		Added:   make(map[string]interface{}),
+		Removed: make(map[string]interface{}),
+		Changed: make(map[string]ValueChange),
+	}
+	switch {
+	case a == nil:
+		d.Status = "added"
+	case b == nil:
+		d.Status = "removed"
+	}
+
+	for k, v := range a {
+		other, ok := b[k]
+		if !ok {
+			d.Removed[k] = mask(v)
+		} else if !reflect.DeepEqual(v, other) {
+			d.Changed[k] = ValueChange{From: mask(v), To: mask(other)}
+		}
+	}
+	for k, v := range b {
+		if _, ok := a[k]; !ok {
+			d.Added[k] = mask(v)
+		}
+	}
+
+	if d.Status == "changed" && len(d.Added)+len(d.Removed)+len(d.Changed) == 0 {
+		return nil
+	}
+	return d
+}
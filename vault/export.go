@@ -0,0 +1,159 @@
+package vault
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// a portable snapshot of a secret subtree
+// secrets are keyed by their path relative to Path, so the document can be imported elsewhere
+type SecretsExport struct {
+	Path    string                            `json:"path"`
+	Secrets map[string]map[string]interface{} `json:"secrets"`
+}
+
+// what an import would do, without writing anything
+type ImportPlan struct {
+	Create    []string `json:"create"`
+	Overwrite []string `json:"overwrite"`
+}
+
+// walks a subtree and reads every secret the caller can access
+// paths that could not be listed or read are returned instead of failing the export
+func (auth AuthInfo) ExportSecrets(path string) (*SecretsExport, []string, error) {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" || !strings.HasSuffix(path, "/") {
+		return nil, nil, errors.New("Path must be a directory ending in '/'")
+	}
+
+	// the root must be listable, otherwise there is nothing to export
+	if _, err := auth.ListSecret(path); err != nil {
+		return nil, nil, err
+	}
+
+	export := &SecretsExport{
+		Path:    path,
+		Secrets: make(map[string]map[string]interface{}),
+	}
+	skipped := []string{}
+	auth.exportTree(path, path, export, &skipped)
+	return export, skipped, nil
+}
+
+func (auth AuthInfo) exportTree(root, path string, export *SecretsExport, skipped *[]string) {
+	keys, err := auth.ListSecret(path)
+	if err != nil {
+		*skipped = append(*skipped, path)
+		return
+	}
+
+	for _, raw := range keys {
+		key, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		if strings.HasSuffix(key, "/") {
+			auth.exportTree(root, path+key, export, skipped)
+			continue
+		}
+		data, err := auth.ReadSecret(path + key)
+		if err != nil {
+			*skipped = append(*skipped, path+key)
+			continue
+		}
+		export.Secrets[strings.TrimPrefix(path+key, root)] = data
+	}
+}
+
+// checks that a document is well formed before anything is written
+func (export *SecretsExport) Validate() error {
+	if export == nil || len(export.Secrets) == 0 {
+		return errors.New("Document contains no secrets")
+	}
+	for rel, data := range export.Secrets {
+		if rel == "" || strings.HasPrefix(rel, "/") || strings.HasSuffix(rel, "/") {
+			return errors.New("Invalid secret path '" + rel + "'")
+		}
+		for _, segment := range strings.Split(rel, "/") {
+			if segment == "" || segment == "." || segment == ".." {
+				return errors.New("Invalid secret path '" + rel + "'")
+			}
+		}
+		if len(data) == 0 {
+			return errors.New("Secret '" + rel + "' has no key value pairs")
+		}
+	}
+	return nil
+}
+
+// returns the relative paths of the document in a stable order
+func (export *SecretsExport) paths() []string {
+	paths := make([]string, 0, len(export.Secrets))
+	for rel := range export.Secrets {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// reports which secrets an import into dest would create and which it would overwrite
+func (auth AuthInfo) PlanImport(dest string, export *SecretsExport) (*ImportPlan, error) {
+	dest, err := importDestination(dest, export)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &ImportPlan{
+		Create:    []string{},
+		Overwrite: []string{},
+	}
+	for _, rel := range export.paths() {
+		exists, err := auth.SecretExists(dest + rel)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			plan.Overwrite = append(plan.Overwrite, dest+rel)
+		} else {
+			plan.Create = append(plan.Create, dest+rel)
+		}
+	}
+	return plan, nil
+}
+
+// writes every secret in the document under dest, in path order
+// returns the paths written so far if a write fails midway
+func (auth AuthInfo) ImportSecrets(dest string, export *SecretsExport) ([]string, error) {
+	dest, err := importDestination(dest, export)
+	if err != nil {
+		return nil, err
+	}
+
+	written := []string{}
+	for _, rel := range export.paths() {
+		if _, err := auth.WriteSecretData(dest+rel, export.Secrets[rel]); err != nil {
+			return written, errors.New("Failed to write " + dest + rel + ": " + err.Error())
+		}
+		written = append(written, dest+rel)
+	}
+	return written, nil
+}
+
+// an import goes to the exported path, unless a different one is specified
+func importDestination(dest string, export *SecretsExport) (string, error) {
+	if err := export.Validate(); err != nil {
+		return "", err
+	}
+	if dest == "" {
+		dest = export.Path
+	}
+	dest = strings.TrimPrefix(dest, "/")
+	if dest == "" {
+		return "", errors.New("Destination path is required")
+	}
+	if !strings.HasSuffix(dest, "/") {
+		dest += "/"
+	}
+	return dest, nil
+}
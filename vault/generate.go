@@ -0,0 +1,314 @@
+package vault
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-uuid"
+	"golang.org/x/crypto/ssh"
+)
+
+// a secret value of the form {"$generate": "<kind>", ...options} is replaced by a generated value
+// keypairs also write the public key to "<key>_public"
+const generateDirective = "$generate"
+
+const (
+	passwordLowercase = "abcdefghijklmnopqrstuvwxyz"
+	passwordUppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passwordDigits    = "0123456789"
+	passwordSymbols   = "!@#$%^&*()-_=+[]{}<>?,.:;"
+)
+
+// options understood by the generators. Unused options are ignored
+type generatorOptions struct {
+	// password
+	Length    int    `json:"length"`
+	Charset   string `json:"charset"`
+	Lowercase *bool  `json:"lowercase"`
+	Uppercase *bool  `json:"uppercase"`
+	Digits    *bool  `json:"digits"`
+	Symbols   *bool  `json:"symbols"`
+	// hex, base64
+	Bytes int `json:"bytes"`
+	// rsa, ssh
+	Bits int `json:"bits"`
+	// ec, and ssh if type is "ecdsa"
+	Curve string `json:"curve"`
+	// ssh
+	Type    string `json:"type"`
+	Comment string `json:"comment"`
+}
+
+// replaces generator directives in a secret with generated values, in place
+// returns the keys that were generated
+func (auth AuthInfo) GenerateSecretValues(data map[string]interface{}) ([]string, error) {
+	// collect directives first, so keypairs can't clobber keys that are being iterated
+	directives := make(map[string]map[string]interface{})
+	for key, v := range data {
+		if obj, ok := v.(map[string]interface{}); ok {
+			if _, ok := obj[generateDirective]; ok {
+				directives[key] = obj
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(directives))
+	for key := range directives {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	generated := []string{}
+	for _, key := range keys {
+		kind, _ := directives[key][generateDirective].(string)
+
+		var opts generatorOptions
+		if b, err := json.Marshal(directives[key]); err != nil {
+			return nil, err
+		} else if err := json.Unmarshal(b, &opts); err != nil {
+			return nil, errors.New(key + ": invalid generator options: " + err.Error())
+		}
+
+		var value, public string
+		var err error
+		switch kind {
+		case "password":
+			value, err = generatePassword(opts)
+		case "hex", "base64":
+			value, err = auth.generateRandomBytes(kind, opts)
+		case "uuid":
+			value, err = uuid.GenerateUUID()
+		case "ssh":
+			value, public, err = generateSSHKeypair(opts)
+		case "rsa":
+			value, public, err = generateRSAKeypair(opts)
+		case "ec":
+			value, public, err = generateECKeypair(opts)
+		default:
+			err = errors.New("unknown generator '" + kind + "'")
+		}
+		if err != nil {
+			return nil, errors.New(key + ": " + err.Error())
+		}
+
+		data[key] = value
+		generated = append(generated, key)
+		if public != "" {
+			if _, exists := data[key+"_public"]; exists {
+				return nil, errors.New(key + ": " + key + "_public is already set")
+			}
+			data[key+"_public"] = public
+			generated = append(generated, key+"_public")
+		}
+	}
+	return generated, nil
+}
+
+func generatePassword(opts generatorOptions) (string, error) {
+	length := opts.Length
+	if length == 0 {
+		length = 24
+	}
+	if length < 4 || length > 1024 {
+		return "", errors.New("length must be between 4 and 1024")
+	}
+
+	// a custom charset replaces the character classes entirely
+	if opts.Charset != "" {
+		return randomString([]rune(opts.Charset), length)
+	}
+
+	enabled := func(b *bool) bool { return b == nil || *b }
+	var classes []string
+	if enabled(opts.Lowercase) {
+		classes = append(classes, passwordLowercase)
+	}
+	if enabled(opts.Uppercase) {
+		classes = append(classes, passwordUppercase)
+	}
+	if enabled(opts.Digits) {
+		classes = append(classes, passwordDigits)
+	}
+	if enabled(opts.Symbols) {
+		classes = append(classes, passwordSymbols)
+	}
+	if len(classes) == 0 {
+		return "", errors.New("at least one character class must be enabled")
+	}
+
+	// at least one character from every enabled class, then shuffle
+	password := []rune{}
+	for _, class := range classes {
+		c, err := randomString([]rune(class), 1)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, []rune(c)...)
+	}
+	rest, err := randomString([]rune(strings.Join(classes, "")), length-len(password))
+	if err != nil {
+		return "", err
+	}
+	password = append(password, []rune(rest)...)
+
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+	return string(password), nil
+}
+
+func randomString(charset []rune, length int) (string, error) {
+	out := make([]rune, length)
+	max := big.NewInt(int64(len(charset)))
+	for i := range out {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		out[i] = charset[n.Int64()]
+	}
+	return string(out), nil
+}
+
+// random bytes come from the transit backend, with the caller's token
+func (auth AuthInfo) generateRandomBytes(format string, opts generatorOptions) (string, error) {
+	n := opts.Bytes
+	if n == 0 {
+		n = 32
+	}
+	if n < 1 || n > 1024 {
+		return "", errors.New("bytes must be between 1 and 1024")
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Logical().Write(
+		GetConfig().TransitBackend+"/random/"+strconv.Itoa(n),
+		map[string]interface{}{
+			"format": format,
+		})
+	if err != nil {
+		return "", err
+	}
+	if resp == nil {
+		return "", errors.New("transit backend did not return random bytes")
+	}
+
+	random, ok := resp.Data["random_bytes"].(string)
+	if !ok {
+		return "", errors.New("Failed type assertion of response to string")
+	}
+	return random, nil
+}
+
+func rsaKey(bits int) (*rsa.PrivateKey, error) {
+	if bits == 0 {
+		bits = 4096
+	}
+	if bits != 2048 && bits != 3072 && bits != 4096 {
+		return nil, errors.New("bits must be 2048, 3072 or 4096")
+	}
+	return rsa.GenerateKey(rand.Reader, bits)
+}
+
+func ecKey(curve string) (*ecdsa.PrivateKey, error) {
+	var c elliptic.Curve
+	switch curve {
+	case "", "p256":
+		c = elliptic.P256()
+	case "p384":
+		c = elliptic.P384()
+	case "p521":
+		c = elliptic.P521()
+	default:
+		return nil, errors.New("curve must be p256, p384 or p521")
+	}
+	return ecdsa.GenerateKey(c, rand.Reader)
+}
+
+// returns a pem encoded private key and a public key in authorized_keys format
+func generateSSHKeypair(opts generatorOptions) (string, string, error) {
+	var private *pem.Block
+	var public interface{}
+
+	switch opts.Type {
+	case "", "rsa":
+		key, err := rsaKey(opts.Bits)
+		if err != nil {
+			return "", "", err
+		}
+		private = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+		public = &key.PublicKey
+	case "ecdsa":
+		key, err := ecKey(opts.Curve)
+		if err != nil {
+			return "", "", err
+		}
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return "", "", err
+		}
+		private = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+		public = &key.PublicKey
+	default:
+		return "", "", errors.New("type must be rsa or ecdsa")
+	}
+
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		return "", "", err
+	}
+	authorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublic)))
+	if opts.Comment != "" {
+		authorized += " " + opts.Comment
+	}
+	return string(pem.EncodeToMemory(private)), authorized, nil
+}
+
+// returns pem encoded PKCS#8 private and PKIX public keys
+func generateRSAKeypair(opts generatorOptions) (string, string, error) {
+	key, err := rsaKey(opts.Bits)
+	if err != nil {
+		return "", "", err
+	}
+	return encodeKeypair(key, &key.PublicKey)
+}
+
+func generateECKeypair(opts generatorOptions) (string, string, error) {
+	key, err := ecKey(opts.Curve)
+	if err != nil {
+		return "", "", err
+	}
+	return encodeKeypair(key, &key.PublicKey)
+}
+
+func encodeKeypair(private, public interface{}) (string, string, error) {
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", "", err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		nil
+}
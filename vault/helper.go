@@ -2,6 +2,7 @@ package vault
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/hashicorp/vault/api"
 )
@@ -72,10 +73,19 @@ func DeleteFromCubbyhole(name string) (*api.Secret, error) {
 }
 
 func WrapData(wrapttl string, data map[string]interface{}) (string, error) {
-	client, err := NewGoldfishVaultClient()
+	wrapInfo, err := WrapDataInfo(wrapttl, data)
 	if err != nil {
 		return "", err
 	}
+	return wrapInfo.Token, nil
+}
+
+// same as WrapData, but also returns the wrapping token's accessor
+func WrapDataInfo(wrapttl string, data map[string]interface{}) (*api.SecretWrapInfo, error) {
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return nil, err
+	}
 
 	client.SetWrappingLookupFunc(func(operation, path string) string {
 		return wrapttl
@@ -83,9 +93,22 @@ func WrapData(wrapttl string, data map[string]interface{}) (string, error) {
 
 	resp, err := client.Logical().Write("/sys/wrapping/wrap", data)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if resp == nil || resp.WrapInfo == nil {
+		return nil, errors.New("Vault did not return a wrapping token")
+	}
+	return resp.WrapInfo, nil
+}
+
+// reads a secret with goldfish's own token, e.g. on behalf of an approved request
+func ReadSecretAsGoldfish(path string) (map[string]interface{}, error) {
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return nil, err
 	}
-	return resp.WrapInfo.Token, nil
+
+	return readSecret(client, path)
 }
 
 func UnwrapData(wrappingToken string) (map[string]interface{}, error) {
@@ -114,3 +137,39 @@ func LookupSelf() (map[string]interface{}, error) {
 	}
 	return resp.Data, nil
 }
+
+// returns the api path of an auth backend, e.g. "auth/userpass"
+// mount is the backend's mount point, or empty for the backend's default
+func authMountPath(mount, defaultMount string) (string, error) {
+	mount = strings.Trim(mount, "/")
+	if mount == "" {
+		mount = defaultMount
+	}
+	for _, segment := range strings.Split(mount, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", errors.New("Invalid auth mount '" + mount + "'")
+		}
+	}
+	return "auth/" + mount, nil
+}
+
+// reads a list of names from an auth backend's response, such as policies or groups
+// vault v0.8.3 and higher returns an array of strings, older versions a comma separated string
+func parseStringList(raw interface{}) []string {
+	list := []string{}
+	switch v := raw.(type) {
+	case []interface{}:
+		for _, each := range v {
+			if s, ok := each.(string); ok && s != "" {
+				list = append(list, s)
+			}
+		}
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
@@ -16,35 +16,61 @@ type LDAPUser struct {
 	Groups   []string
 }
 
-func (auth AuthInfo) ListLDAPGroups() ([]LDAPGroup, error) {
+// returns the api path of a group or user in an ldap backend. An empty mount means "ldap"
+func ldapEntryPath(mount, kind, name string) (string, error) {
+	path, err := authMountPath(mount, "ldap")
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(name) == "" || strings.Contains(name, "/") {
+		return "", errors.New("Invalid " + strings.TrimSuffix(kind, "s") + " name")
+	}
+	return path + "/" + kind + "/" + name, nil
+}
+
+// lists the names under a path, ignoring any that somehow can't be type asserted to string
+func (auth AuthInfo) listNames(path string) ([]string, error) {
 	client, err := auth.Client()
 	if err != nil {
 		return nil, err
 	}
-	logical := client.Logical()
 
-	resp, err := logical.List("auth/ldap/groups")
+	resp, err := client.Logical().List(path)
 	if err != nil {
 		return nil, err
 	}
-
-	// if there are no ldap groups, return an empty slice
 	if resp == nil || resp.Data == nil {
-		return []LDAPGroup{}, nil
+		return []string{}, nil
 	}
 
 	raw, ok := resp.Data["keys"].([]interface{})
 	if !ok {
-		return nil, errors.New("Failed to fetch LDAP group names")
+		return nil, errors.New("Failed to fetch names under " + path)
 	}
-
-	// ignore any group names that somehow can't be type asserted to string
-	var groups []string
+	names := []string{}
 	for _, each := range raw {
-		if group, ok := each.(string); ok {
-			groups = append(groups, group)
+		if name, ok := each.(string); ok {
+			names = append(names, name)
 		}
 	}
+	return names, nil
+}
+
+func (auth AuthInfo) ListLDAPGroups(mount string) ([]LDAPGroup, error) {
+	path, err := authMountPath(mount, "ldap")
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := auth.listNames(path + "/groups")
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
 
 	results := make([]LDAPGroup, len(groups))
 	for i, group := range groups {
@@ -53,55 +79,29 @@ func (auth AuthInfo) ListLDAPGroups() ([]LDAPGroup, error) {
 		}
 
 		// fetch group's policies
-		resp, err := logical.Read("auth/ldap/groups/" + group)
+		resp, err := client.Logical().Read(path + "/groups/" + group)
 		if err == nil && resp != nil {
-			if raw, ok := resp.Data["policies"]; ok {
-				// vault v0.8.3 and higher returns an array of strings
-				if policies, ok := raw.([]interface{}); ok {
-					for _, p := range policies {
-						if s, ok := p.(string); ok {
-							results[i].Policies = append(results[i].Policies, s)
-						}
-					}
-				// vault v0.8.2 and lower has a different JSON response
-				} else if policies, ok := raw.(string); ok {
-					results[i].Policies = strings.Split(policies, ",")
-				}
-			}
+			results[i].Policies = parseStringList(resp.Data["policies"])
 		}
 	}
 
 	return results, nil
 }
 
-func (auth AuthInfo) ListLDAPUsers() ([]LDAPUser, error) {
-	client, err := auth.Client()
+func (auth AuthInfo) ListLDAPUsers(mount string) ([]LDAPUser, error) {
+	path, err := authMountPath(mount, "ldap")
 	if err != nil {
 		return nil, err
 	}
-	logical := client.Logical()
 
-	resp, err := logical.List("auth/ldap/users")
+	users, err := auth.listNames(path + "/users")
 	if err != nil {
 		return nil, err
 	}
 
-	// if there are no ldap users, return an empty slice
-	if resp == nil || resp.Data == nil {
-		return []LDAPUser{}, nil
-	}
-
-	raw, ok := resp.Data["keys"].([]interface{})
-	if !ok {
-		return nil, errors.New("Failed to fetch LDAP usernames")
-	}
-
-	// ignore any user names that somehow can't be type asserted to string
-	var users []string
-	for _, each := range raw {
-		if user, ok := each.(string); ok {
-			users = append(users, user)
-		}
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
 	}
 
 	results := make([]LDAPUser, len(users))
@@ -111,30 +111,108 @@ func (auth AuthInfo) ListLDAPUsers() ([]LDAPUser, error) {
 		}
 
 		// fetch user's policies and groups
-		resp, err := logical.Read("auth/ldap/users/" + user)
+		resp, err := client.Logical().Read(path + "/users/" + user)
 		if err != nil || resp == nil {
 			continue
 		}
+		results[i].Policies = parseStringList(resp.Data["policies"])
+		results[i].Groups = parseStringList(resp.Data["groups"])
+	}
 
-		if raw, ok := resp.Data["policies"]; ok {
-			// vault v0.8.3 and higher returns an array of strings
-			if policies, ok := raw.([]interface{}); ok {
-				for _, p := range policies {
-					if s, ok := p.(string); ok {
-						results[i].Policies = append(results[i].Policies, s)
-					}
-				}
-			// vault v0.8.2 and lower has a different JSON response
-			} else if policies, ok := raw.(string); ok {
-				results[i].Policies = strings.Split(policies, ",")
-			}
-		}
-		if raw, ok := resp.Data["groups"]; ok {
-			if groups, ok := raw.(string); ok {
-				results[i].Groups = strings.Split(groups, ",")
-			}
+	return results, nil
+}
+
+// maps an ldap group to policies, replacing any existing mapping
+func (auth AuthInfo) WriteLDAPGroup(mount string, g LDAPGroup) error {
+	path, err := ldapEntryPath(mount, "groups", g.Name)
+	if err != nil {
+		return err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Write(path, map[string]interface{}{
+		"policies": strings.Join(sortedPolicies(g.Policies), ","),
+	})
+	return err
+}
+
+func (auth AuthInfo) DeleteLDAPGroup(mount, name string) error {
+	path, err := ldapEntryPath(mount, "groups", name)
+	if err != nil {
+		return err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Delete(path)
+	return err
+}
+
+// maps an ldap user to policies and extra groups, replacing any existing mapping
+func (auth AuthInfo) WriteLDAPUser(mount string, u LDAPUser) error {
+	path, err := ldapEntryPath(mount, "users", u.Name)
+	if err != nil {
+		return err
+	}
+	for _, g := range u.Groups {
+		if strings.TrimSpace(g) == "" || strings.Contains(g, ",") {
+			return errors.New("Group names must not be empty or contain ','")
 		}
 	}
 
-	return results, nil
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Write(path, map[string]interface{}{
+		"policies": strings.Join(sortedPolicies(u.Policies), ","),
+		"groups":   strings.Join(u.Groups, ","),
+	})
+	return err
+}
+
+func (auth AuthInfo) DeleteLDAPUser(mount, name string) error {
+	path, err := ldapEntryPath(mount, "users", name)
+	if err != nil {
+		return err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Delete(path)
+	return err
+}
+
+// returns an ldap backend's configuration. The bind password is never returned
+func (auth AuthInfo) GetLDAPConfig(mount string) (map[string]interface{}, error) {
+	path, err := authMountPath(mount, "ldap")
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Logical().Read(path + "/config")
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Data == nil {
+		return nil, errors.New("LDAP backend at " + path + " is not configured")
+	}
+
+	config := resp.Data
+	if pass, ok := config["bindpass"].(string); ok && pass != "" {
+		config["bindpass"] = "(redacted)"
+	}
+	return config, nil
 }
@@ -0,0 +1,228 @@
+package vault
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// number of leases shown in a revoke-prefix dry run
+const leaseRevokeSampleSize = 20
+
+type LeaseInfo struct {
+	ID          string `json:"id"`
+	IssueTime   string `json:"issue_time"`
+	ExpireTime  string `json:"expire_time"`
+	LastRenewal string `json:"last_renewal"`
+	Renewable   bool   `json:"renewable"`
+	TTL         int64  `json:"ttl"`
+	Error       string `json:"error,omitempty"`
+}
+
+// one level of the lease tree. Folders end in '/'
+type LeaseListing struct {
+	Prefix  string      `json:"prefix"`
+	Folders []string    `json:"folders"`
+	Leases  []LeaseInfo `json:"leases"`
+}
+
+// what revoking a prefix would do, without revoking anything
+type LeaseRevokePlan struct {
+	Prefix string   `json:"prefix"`
+	Count  int      `json:"count"`
+	Sample []string `json:"sample"`
+}
+
+// lease prefixes are the paths that issued them, e.g. "aws/creds/deploy/"
+func normalizeLeasePrefix(prefix string) string {
+	prefix = strings.TrimPrefix(prefix, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix
+}
+
+func (auth AuthInfo) listLeaseKeys(prefix string) ([]string, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Logical().List("sys/leases/lookup/" + prefix)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Data == nil {
+		return []string{}, nil
+	}
+
+	raw, _ := resp.Data["keys"].([]interface{})
+	keys := make([]string, 0, len(raw))
+	for _, k := range raw {
+		if s, ok := k.(string); ok {
+			keys = append(keys, s)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// lists the folders and leases directly under a prefix, with each lease's details
+func (auth AuthInfo) ListLeases(prefix string) (*LeaseListing, error) {
+	prefix = normalizeLeasePrefix(prefix)
+
+	keys, err := auth.listLeaseKeys(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	listing := &LeaseListing{
+		Prefix:  prefix,
+		Folders: []string{},
+	}
+	ids := []string{}
+	for _, k := range keys {
+		if strings.HasSuffix(k, "/") {
+			listing.Folders = append(listing.Folders, k)
+		} else {
+			ids = append(ids, prefix+k)
+		}
+	}
+
+	listing.Leases, err = auth.lookupLeases(ids)
+	if err != nil {
+		return nil, err
+	}
+	return listing, nil
+}
+
+// looks up leases concurrently. Leases that could not be looked up carry an error
+func (auth AuthInfo) lookupLeases(ids []string) ([]LeaseInfo, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	leases := make([]LeaseInfo, len(ids))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < accessorLookupWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				leases[i].ID = ids[i]
+				resp, err := client.Logical().Write("sys/leases/lookup", map[string]interface{}{
+					"lease_id": ids[i],
+				})
+				switch {
+				case err != nil:
+					leases[i].Error = err.Error()
+				case resp == nil || resp.Data == nil:
+					leases[i].Error = "Empty response from vault"
+				default:
+					leases[i].IssueTime = tokenString(resp.Data, "issue_time")
+					leases[i].ExpireTime = tokenString(resp.Data, "expire_time")
+					leases[i].LastRenewal = tokenString(resp.Data, "last_renewal")
+					leases[i].Renewable, _ = resp.Data["renewable"].(bool)
+					leases[i].TTL = tokenInt(resp.Data, "ttl")
+				}
+			}
+		}()
+	}
+	for i := range ids {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return leases, nil
+}
+
+// renews a lease, optionally requesting an increment in seconds
+func (auth AuthInfo) RenewLease(id string, increment int) (*LeaseInfo, error) {
+	if id == "" {
+		return nil, errors.New("Empty lease id")
+	}
+	if increment < 0 {
+		return nil, errors.New("Increment must not be negative")
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := client.Sys().Renew(id, increment); err != nil {
+		return nil, err
+	}
+
+	leases, err := auth.lookupLeases([]string{id})
+	if err != nil {
+		return nil, err
+	}
+	return &leases[0], nil
+}
+
+func (auth AuthInfo) RevokeLease(id string) error {
+	if id == "" {
+		return errors.New("Empty lease id")
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	return client.Sys().Revoke(id)
+}
+
+// counts every lease under a prefix, at any depth
+func (auth AuthInfo) PlanRevokeLeasePrefix(prefix string) (*LeaseRevokePlan, error) {
+	prefix = normalizeLeasePrefix(prefix)
+	if prefix == "" {
+		return nil, errors.New("Refusing to revoke every lease in vault: a prefix is required")
+	}
+
+	plan := &LeaseRevokePlan{
+		Prefix: prefix,
+		Sample: []string{},
+	}
+
+	folders := []string{prefix}
+	for len(folders) > 0 {
+		folder := folders[0]
+		folders = folders[1:]
+
+		keys, err := auth.listLeaseKeys(folder)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			if strings.HasSuffix(k, "/") {
+				folders = append(folders, folder+k)
+				continue
+			}
+			plan.Count++
+			if len(plan.Sample) < leaseRevokeSampleSize {
+				plan.Sample = append(plan.Sample, folder+k)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// revokes every lease under a prefix
+func (auth AuthInfo) RevokeLeasePrefix(prefix string) error {
+	prefix = normalizeLeasePrefix(prefix)
+	if prefix == "" {
+		return errors.New("Refusing to revoke every lease in vault: a prefix is required")
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	return client.Sys().RevokePrefix(prefix)
+}
@@ -53,19 +53,37 @@ func (auth *AuthInfo) Login() (map[string]interface{}, error) {
 			loginPath = "auth/" + auth.Path + "/login/" + auth.ID
 		}
 
-		resp, err := client.Logical().Write(
-			loginPath,
-			map[string]interface{}{
-				key: auth.Pass,
-			})
+		if err := loginWrite(client, loginPath, map[string]interface{}{
+			key: auth.Pass,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// approle logins send the role id in the body instead of the path
+	if t == "approle" {
+		loginPath := "auth/approle/login"
+		if auth.Path != "" {
+			loginPath = "auth/" + auth.Path + "/login"
+		}
+
+		if err := loginWrite(client, loginPath, map[string]interface{}{
+			"role_id": auth.ID,
+			key:       auth.Pass,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// wrapped logins unwrap a response that holds a client token, e.g. from 'vault token-create -wrap-ttl'
+	if t == "wrapped" {
+		resp, err := client.Logical().Unwrap(auth.ID)
 		if err != nil {
 			return nil, err
 		}
-		// sanity check to make sure client token exists
-		if resp.Auth == nil || resp.Auth.ClientToken == "" {
-			return nil, errors.New("Unable to parse vault response")
+		if resp == nil || resp.Auth == nil || resp.Auth.ClientToken == "" {
+			return nil, errors.New("Wrapping token does not contain a client token")
 		}
-		// set the returned client token as the client's auth
 		client.SetToken(resp.Auth.ClientToken)
 	}
 
@@ -83,6 +101,20 @@ func (auth *AuthInfo) Login() (map[string]interface{}, error) {
 	return lookupResp.Data, nil
 }
 
+// fetches a client token by writing to an auth backend's login path, and sets it as the client's auth
+func loginWrite(client *api.Client, path string, data map[string]interface{}) error {
+	resp, err := client.Logical().Write(path, data)
+	if err != nil {
+		return err
+	}
+	// sanity check to make sure client token exists
+	if resp == nil || resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return errors.New("Unable to parse vault response")
+	}
+	client.SetToken(resp.Auth.ClientToken)
+	return nil
+}
+
 func (auth AuthInfo) RenewSelf() (*api.Secret, error) {
 	client, err := auth.Client()
 	if err != nil {
@@ -101,9 +133,11 @@ func (auth AuthInfo) LookupSelf() (*api.Secret, error) {
 
 // Logging in with different methods requires different secondary keys
 var LoginMap = map[string]string{
-    "token": "",
+	"token":    "",
 	"userpass": "password",
-	"github": "token",
-	"ldap": "password",
-	"okta": "password",
+	"github":   "token",
+	"ldap":     "password",
+	"okta":     "password",
+	"approle":  "secret_id",
+	"wrapped":  "",
 }
@@ -2,7 +2,9 @@ package vault
 
 import (
 	"errors"
+	"strings"
 
+	"github.com/fatih/structs"
 	"github.com/hashicorp/vault/api"
 )
 
@@ -41,3 +43,141 @@ func (auth AuthInfo) TuneMount(path string, config api.MountConfigInput) error {
 
 	return client.Sys().TuneMount(path+"/", config)
 }
+
+// settings accepted when enabling a secret engine
+// options are passed through to vault, e.g. {"version": "2"} for kv
+type MountRequest struct {
+	Type        string               `json:"type"`
+	Description string               `json:"description"`
+	Config      api.MountConfigInput `json:"config"`
+	Options     map[string]string    `json:"options"`
+	Local       bool                 `json:"local"`
+	SealWrap    bool                 `json:"seal_wrap"`
+}
+
+// what unmounting or remounting an engine would affect
+type MountImpact struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	// -1 if the engine does not keep listable secrets
+	Secrets int `json:"secrets"`
+	// directories that could not be listed, so were not counted
+	Skipped []string `json:"skipped"`
+}
+
+// engines whose secrets can be counted by listing
+var countableMountTypes = map[string]bool{
+	"kv":      true,
+	"generic": true,
+}
+
+func (auth AuthInfo) Mount(path string, m MountRequest) error {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return errors.New("Empty mount name")
+	}
+	if m.Type == "" {
+		return errors.New("Mount type is required")
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+
+	// written directly, since the api's MountInput has no options
+	_, err = client.Logical().Write("sys/mounts/"+path, map[string]interface{}{
+		"type":        m.Type,
+		"description": m.Description,
+		"config":      structs.Map(m.Config),
+		"options":     m.Options,
+		"local":       m.Local,
+		"seal_wrap":   m.SealWrap,
+	})
+	forgetKVMounts()
+	return err
+}
+
+// finds the engine at a path and counts the secrets it holds
+func (auth AuthInfo) PlanUnmount(path string) (*MountImpact, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, errors.New("Empty mount name")
+	}
+
+	mounts, err := auth.ListMounts()
+	if err != nil {
+		return nil, err
+	}
+	mount, ok := mounts[path+"/"]
+	if !ok {
+		return nil, errors.New("No secret engine is mounted at " + path)
+	}
+
+	impact := &MountImpact{
+		Path:    path + "/",
+		Type:    mount.Type,
+		Secrets: -1,
+		Skipped: []string{},
+	}
+	if countableMountTypes[mount.Type] {
+		impact.Secrets = auth.countSecrets(path+"/", &impact.Skipped)
+	}
+	return impact, nil
+}
+
+func (auth AuthInfo) countSecrets(path string, skipped *[]string) int {
+	keys, err := auth.ListSecret(path)
+	if err != nil {
+		*skipped = append(*skipped, path)
+		return 0
+	}
+
+	count := 0
+	for _, raw := range keys {
+		key, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		if strings.HasSuffix(key, "/") {
+			count += auth.countSecrets(path+key, skipped)
+		} else {
+			count++
+		}
+	}
+	return count
+}
+
+// disables a secret engine. Every secret in it is destroyed and its leases are revoked
+func (auth AuthInfo) Unmount(path string) error {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return errors.New("Empty mount name")
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+
+	err = client.Sys().Unmount(path)
+	forgetKVMounts()
+	return err
+}
+
+// moves a secret engine. Its secrets are kept, but its leases are revoked
+func (auth AuthInfo) Remount(from, to string) error {
+	from, to = strings.Trim(from, "/"), strings.Trim(to, "/")
+	if from == "" || to == "" {
+		return errors.New("Empty mount name")
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+
+	err = client.Sys().Remount(from, to)
+	forgetKVMounts()
+	return err
+}
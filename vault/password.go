@@ -0,0 +1,179 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// rules that self-service password changes must satisfy
+// configured in the run-time config as json, e.g.
+// {"min_length": 12, "min_classes": 3, "banned": ["password", "goldfish"]}
+type PasswordPolicy struct {
+	MinLength int `json:"min_length"`
+	// how many of lowercase, uppercase, digits and symbols must be present
+	MinClasses int `json:"min_classes"`
+	// passwords containing any of these, case insensitively, are refused
+	Banned []string `json:"banned"`
+}
+
+// applies when the run-time config has no password policy
+var defaultPasswordPolicy = PasswordPolicy{
+	MinLength: 8,
+}
+
+func parsePasswordPolicy(raw string) (PasswordPolicy, error) {
+	if raw == "" {
+		return defaultPasswordPolicy, nil
+	}
+
+	var p PasswordPolicy
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		return p, errors.New("PasswordPolicy is not valid json: " + err.Error())
+	}
+	if p.MinLength < 1 {
+		return p, errors.New("PasswordPolicy: min_length must be at least 1")
+	}
+	if p.MinClasses < 0 || p.MinClasses > 4 {
+		return p, errors.New("PasswordPolicy: min_classes must be between 0 and 4")
+	}
+	return p, nil
+}
+
+// returns the first rule that the password breaks
+func (p PasswordPolicy) Validate(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return errors.New("Password must be at least " + strconv.Itoa(p.MinLength) + " characters long")
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	if classes < p.MinClasses {
+		return errors.New("Password must contain at least " + strconv.Itoa(p.MinClasses) +
+			" of: lowercase letters, uppercase letters, digits, symbols")
+	}
+
+	lowered := strings.ToLower(password)
+	for _, banned := range p.Banned {
+		if banned != "" && strings.Contains(lowered, strings.ToLower(banned)) {
+			return errors.New("Password contains a banned word")
+		}
+	}
+	return nil
+}
+
+// changes the password of the userpass user that owns this token
+// the current password is verified with a fresh login first. The new password is written with
+// the caller's own token, so their policy must allow updating their own password entry
+func (auth AuthInfo) ChangeOwnPassword(current, proposed string) error {
+	self, err := auth.LookupSelf()
+	if err != nil {
+		return err
+	}
+	if self == nil {
+		return errors.New("Could not look up the current token")
+	}
+
+	// a userpass token's path is auth/<mount>/login/<username>
+	loginPath, _ := self.Data["path"].(string)
+	if !strings.HasPrefix(loginPath, "auth/") || !strings.Contains(loginPath, "/login/") {
+		return errors.New("Only users that logged in with a username and password can change it here")
+	}
+	parts := strings.SplitN(strings.TrimPrefix(loginPath, "auth/"), "/login/", 2)
+	mount, username := parts[0], parts[1]
+
+	// ldap, okta and others share the same login path shape
+	mountType, err := auth.authMountType(mount)
+	if err != nil {
+		return err
+	}
+	if mountType != "userpass" {
+		return errors.New("Only users that logged in with a username and password can change it here")
+	}
+	if meta, ok := self.Data["meta"].(map[string]interface{}); ok {
+		if name, ok := meta["username"].(string); ok && name != "" {
+			username = name
+		}
+	}
+
+	if current == "" {
+		return errors.New("Current password is required")
+	}
+	if proposed == current {
+		return errors.New("New password must differ from the current one")
+	}
+	if err := getPasswordPolicy().Validate(proposed); err != nil {
+		return err
+	}
+
+	// re-verify the current password. The login's token is only proof, so it is revoked
+	verify := &AuthInfo{Type: "userpass", ID: username, Pass: current, Path: mount}
+	if _, err := verify.Login(); err != nil {
+		if strings.Contains(err.Error(), "invalid username or password") {
+			return errors.New("Current password is incorrect")
+		}
+		return err
+	}
+	verify.RevokeSelf()
+	verify.Clear()
+
+	path, err := userpassUserPath(mount, username)
+	if err != nil {
+		return err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Write(path+"/password", map[string]interface{}{
+		"password": proposed,
+	})
+	return err
+}
+
+// returns the type of the auth backend mounted at mount, e.g. "userpass"
+// sys/auth is read with the caller's token if it may, otherwise with goldfish's own
+func (auth AuthInfo) authMountType(mount string) (string, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Logical().Read("sys/auth")
+	if err != nil || resp == nil {
+		if client, err = NewGoldfishVaultClient(); err != nil {
+			return "", err
+		}
+		if resp, err = client.Logical().Read("sys/auth"); err != nil {
+			return "", err
+		}
+	}
+	if resp == nil {
+		return "", errors.New("Could not read auth backends")
+	}
+
+	m, _ := resp.Data[strings.TrimSuffix(mount, "/")+"/"].(map[string]interface{})
+	if m == nil {
+		return "", errors.New("Auth backend " + mount + " not found")
+	}
+	t, _ := m["type"].(string)
+	return t, nil
+}
@@ -0,0 +1,175 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// constraints on the secrets written under a path prefix
+// schemas are configured in the run-time config as a json object keyed by prefix, e.g.
+// {"secret/apps/": {"required": ["DB_PASSWORD"], "forbidden": ["DB_PASS"], "types": {"DB_PORT": "number"}}}
+type SecretSchema struct {
+	Required []string `json:"required"`
+	// one of "string", "number", "boolean", "object" or "array"
+	Types     map[string]string `json:"types"`
+	Patterns  map[string]string `json:"patterns"`
+	Forbidden []string          `json:"forbidden"`
+
+	prefix   string
+	compiled map[string]*regexp.Regexp
+}
+
+type SchemaViolation struct {
+	Key   string `json:"key"`
+	Error string `json:"error"`
+}
+
+// returned by secret writes that do not satisfy their path's schema
+type SchemaError struct {
+	Path       string
+	Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.Key+": "+v.Error)
+	}
+	return "Secret does not match the schema for " + e.Path + ": " + strings.Join(msgs, ", ")
+}
+
+var validSchemaTypes = map[string]bool{
+	"string":  true,
+	"number":  true,
+	"boolean": true,
+	"object":  true,
+	"array":   true,
+}
+
+// parses and compiles the schemas in the run-time config, longest prefix first
+func parseSecretSchemas(raw string) ([]*SecretSchema, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var byPrefix map[string]*SecretSchema
+	if err := json.Unmarshal([]byte(raw), &byPrefix); err != nil {
+		return nil, errors.New("SecretSchemas is not valid json: " + err.Error())
+	}
+
+	schemas := make([]*SecretSchema, 0, len(byPrefix))
+	for prefix, s := range byPrefix {
+		if s == nil {
+			continue
+		}
+		s.prefix = strings.TrimPrefix(prefix, "/")
+		s.compiled = make(map[string]*regexp.Regexp)
+		for key, t := range s.Types {
+			if !validSchemaTypes[t] {
+				return nil, errors.New("SecretSchemas: unknown type '" + t + "' for key " + key)
+			}
+		}
+		for key, pattern := range s.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, errors.New("SecretSchemas: invalid pattern for key " + key + ": " + err.Error())
+			}
+			s.compiled[key] = re
+		}
+		schemas = append(schemas, s)
+	}
+
+	sort.Slice(schemas, func(i, j int) bool {
+		return len(schemas[i].prefix) > len(schemas[j].prefix)
+	})
+	return schemas, nil
+}
+
+// returns the most specific schema that applies to a path, or nil
+func schemaFor(path string) *SecretSchema {
+	path = strings.TrimPrefix(path, "/")
+	for _, s := range getSecretSchemas() {
+		if strings.HasPrefix(path, s.prefix) {
+			return s
+		}
+	}
+	return nil
+}
+
+// checks a secret against its path's schema. Returns a *SchemaError if it does not match
+func ValidateSecret(path string, data map[string]interface{}) error {
+	s := schemaFor(path)
+	if s == nil {
+		return nil
+	}
+
+	violations := s.validate(data)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &SchemaError{
+		Path:       s.prefix,
+		Violations: violations,
+	}
+}
+
+func (s *SecretSchema) validate(data map[string]interface{}) []SchemaViolation {
+	violations := []SchemaViolation{}
+
+	for _, key := range s.Required {
+		if _, ok := data[key]; !ok {
+			violations = append(violations, SchemaViolation{key, "is required"})
+		}
+	}
+
+	for _, key := range s.Forbidden {
+		if _, ok := data[key]; ok {
+			violations = append(violations, SchemaViolation{key, "is not allowed"})
+		}
+	}
+
+	for key, t := range s.Types {
+		if v, ok := data[key]; ok && jsonType(v) != t {
+			violations = append(violations, SchemaViolation{key, "must be of type " + t})
+		}
+	}
+
+	for key, re := range s.compiled {
+		v, ok := data[key]
+		if !ok {
+			continue
+		}
+		str, isString := v.(string)
+		if !isString {
+			violations = append(violations, SchemaViolation{key, "must be a string matching " + re.String()})
+		} else if !re.MatchString(str) {
+			violations = append(violations, SchemaViolation{key, "must match " + re.String()})
+		}
+	}
+
+	// map iteration is random, but errors should be reported consistently
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Key < violations[j].Key
+	})
+	return violations
+}
+
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64, json.Number, int, int64:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return ""
+	}
+}
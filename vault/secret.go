@@ -3,6 +3,28 @@ package vault
 import (
 	"encoding/json"
 	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// the kv mount that a secret path belongs to
+// Version is 1 for generic/kv v1 mounts, and for mounts that could not be inspected
+type KVMount struct {
+	Path    string
+	Version int
+}
+
+// mount options rarely change, so detection results are kept for a short while
+const kvMountCacheTTL = time.Minute
+
+var (
+	kvMountCache     = make(map[string]KVMount)
+	kvMountCacheTime time.Time
+	kvMountCacheLock = new(sync.RWMutex)
 )
 
 func (auth AuthInfo) ListSecret(path string) ([]interface{}, error) {
@@ -11,7 +33,8 @@ func (auth AuthInfo) ListSecret(path string) ([]interface{}, error) {
 		return nil, err
 	}
 
-	resp, err := client.Logical().List(path)
+	mount := kvMountFor(client, path)
+	resp, err := client.Logical().List(mount.apiPath("metadata", path))
 	if err != nil {
 		return nil, err
 	}
@@ -29,39 +52,326 @@ func (auth AuthInfo) ReadSecret(path string) (map[string]interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	return readSecret(client, path)
+}
+
+func (auth AuthInfo) WriteSecret(path string, raw string) (interface{}, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
 
-	resp, err := client.Logical().Read(path)
+	var data map[string]interface{}
+	err = json.Unmarshal([]byte(raw), &data)
 	if err != nil {
 		return nil, err
 	}
 
+	return writeSecret(client, path, data)
+}
+
+// same as WriteSecret, for data that is already decoded
+func (auth AuthInfo) WriteSecretData(path string, data map[string]interface{}) (*api.Secret, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+	return writeSecret(client, path, data)
+}
+
+// a deleted kv v2 secret does not count as existing
+func (auth AuthInfo) SecretExists(path string) (bool, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return false, err
+	}
+
+	mount := kvMountFor(client, path)
+	resp, err := client.Logical().Read(mount.apiPath("data", path))
+	if err != nil {
+		return false, err
+	}
 	if resp == nil {
-		// invalid handler (i.e. invalid request)
-		return nil, errors.New("Invalid path")
-	} else {
-		return resp.Data, nil
+		return false, nil
+	}
+	if mount.Version == 2 {
+		_, ok := resp.Data["data"].(map[string]interface{})
+		return ok, nil
 	}
+	return true, nil
 }
 
-func (auth AuthInfo) WriteSecret(path string, raw string) (interface{}, error) {
+// on a kv v2 mount, this soft deletes the latest version
+func (auth AuthInfo) DeleteSecret(path string) (interface{}, error) {
 	client, err := auth.Client()
 	if err != nil {
 		return nil, err
 	}
 
-	var data map[string]interface{}
-	err = json.Unmarshal([]byte(raw), &data)
+	mount := kvMountFor(client, path)
+	return client.Logical().Delete(mount.apiPath("data", path))
+}
+
+// removes a secret entirely. On a kv v2 mount, every version and the metadata are destroyed
+func (auth AuthInfo) RemoveSecret(path string) (interface{}, error) {
+	client, err := auth.Client()
 	if err != nil {
 		return nil, err
 	}
 
-	return client.Logical().Write(path, data)
+	mount := kvMountFor(client, path)
+	return client.Logical().Delete(mount.apiPath("metadata", path))
 }
 
-func (auth AuthInfo) DeleteSecret(path string) (interface{}, error) {
+// returns the caller's capabilities on a secret's data
+// kv v2 policies are written against <mount>/data/<key>, not the logical path
+func (auth AuthInfo) SecretCapabilities(path string) ([]string, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return []string{}, err
+	}
+
+	mount := kvMountFor(client, path)
+	return client.Sys().CapabilitiesSelf(mount.apiPath("data", path))
+}
+
+// returns the kv mount and version that a path belongs to
+func (auth AuthInfo) KVMount(path string) (KVMount, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return KVMount{}, err
+	}
+	return kvMountFor(client, path), nil
+}
+
+// reads a specific version of a kv v2 secret, and that version's metadata
+func (auth AuthInfo) ReadSecretVersion(path string, version int) (map[string]interface{}, map[string]interface{}, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mount := kvMountFor(client, path)
+	if mount.Version != 2 {
+		return nil, nil, errors.New("Versions are only supported on kv version 2 mounts")
+	}
+
+	r := client.NewRequest("GET", "/v1/"+mount.apiPath("data", path))
+	r.Params.Set("version", strconv.Itoa(version))
+	resp, err := client.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if resp != nil && resp.StatusCode == 404 {
+		return nil, nil, errors.New("Version not found, or it has been deleted")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	secret, err := api.ParseSecret(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil, errors.New("Invalid path")
+	}
+
+	data, _ := secret.Data["data"].(map[string]interface{})
+	metadata, _ := secret.Data["metadata"].(map[string]interface{})
+	return data, metadata, nil
+}
+
+// returns the metadata of a kv v2 secret, including created_time and version history
+func (auth AuthInfo) ReadSecretMetadata(path string) (map[string]interface{}, error) {
 	client, err := auth.Client()
 	if err != nil {
 		return nil, err
 	}
-	return client.Logical().Delete(path)
+
+	mount := kvMountFor(client, path)
+	if mount.Version != 2 {
+		return nil, errors.New("Metadata is only supported on kv version 2 mounts")
+	}
+
+	resp, err := client.Logical().Read(mount.apiPath("metadata", path))
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("Invalid path")
+	}
+	return resp.Data, nil
+}
+
+// soft deletes versions of a kv v2 secret. They can be recovered with UndeleteSecretVersions
+func (auth AuthInfo) DeleteSecretVersions(path string, versions []int) error {
+	return auth.secretVersionsOp("delete", path, versions)
+}
+
+func (auth AuthInfo) UndeleteSecretVersions(path string, versions []int) error {
+	return auth.secretVersionsOp("undelete", path, versions)
+}
+
+// permanently removes the data of versions of a kv v2 secret
+func (auth AuthInfo) DestroySecretVersions(path string, versions []int) error {
+	return auth.secretVersionsOp("destroy", path, versions)
+}
+
+func (auth AuthInfo) secretVersionsOp(op, path string, versions []int) error {
+	if len(versions) == 0 {
+		return errors.New("At least one version must be specified")
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+
+	mount := kvMountFor(client, path)
+	if mount.Version != 2 {
+		return errors.New("Versions are only supported on kv version 2 mounts")
+	}
+
+	_, err = client.Logical().Write(mount.apiPath(op, path), map[string]interface{}{
+		"versions": versions,
+	})
+	return err
+}
+
+// reads a secret's key value pairs, unwrapping the kv v2 response shape if needed
+func readSecret(client *api.Client, path string) (map[string]interface{}, error) {
+	mount := kvMountFor(client, path)
+	resp, err := client.Logical().Read(mount.apiPath("data", path))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil {
+		// invalid handler (i.e. invalid request)
+		return nil, errors.New("Invalid path")
+	}
+	if mount.Version == 2 {
+		data, ok := resp.Data["data"].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("Secret has been deleted or destroyed")
+		}
+		return data, nil
+	}
+	return resp.Data, nil
+}
+
+func writeSecret(client *api.Client, path string, data map[string]interface{}) (*api.Secret, error) {
+	if err := ValidateSecret(path, data); err != nil {
+		return nil, err
+	}
+
+	mount := kvMountFor(client, path)
+	if mount.Version == 2 {
+		return client.Logical().Write(mount.apiPath("data", path), map[string]interface{}{
+			"data": data,
+		})
+	}
+	return client.Logical().Write(path, data)
+}
+
+// rewrites a logical path to the kv v2 api path, e.g. secret/foo -> secret/data/foo
+// paths on other mounts are returned unchanged
+func (m KVMount) apiPath(prefix, path string) string {
+	if m.Version != 2 {
+		return path
+	}
+	rest := strings.TrimPrefix(strings.TrimPrefix(path, "/"), m.Path)
+	if rest == strings.TrimSuffix(m.Path, "/") {
+		rest = ""
+	}
+	return m.Path + prefix + "/" + rest
+}
+
+// finds the mount of a path, using the ui mounts endpoint which any token with
+// access to the path may read. If that is unavailable, sys/mounts is tried instead
+func kvMountFor(client *api.Client, path string) KVMount {
+	path = strings.TrimPrefix(path, "/")
+
+	kvMountCacheLock.RLock()
+	if time.Since(kvMountCacheTime) < kvMountCacheTTL {
+		if m, ok := matchKVMount(kvMountCache, path); ok {
+			kvMountCacheLock.RUnlock()
+			return m
+		}
+	}
+	kvMountCacheLock.RUnlock()
+
+	var found []KVMount
+	if resp, err := client.Logical().Read("sys/internal/ui/mounts/" + path); err == nil && resp != nil {
+		if mountPath, ok := resp.Data["path"].(string); ok && mountPath != "" {
+			found = append(found, KVMount{
+				Path:    mountPath,
+				Version: kvVersion(resp.Data["type"], resp.Data["options"]),
+			})
+		}
+	}
+	if found == nil {
+		if resp, err := client.Logical().Read("sys/mounts"); err == nil && resp != nil {
+			for mountPath, raw := range resp.Data {
+				mount, ok := raw.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				found = append(found, KVMount{
+					Path:    mountPath,
+					Version: kvVersion(mount["type"], mount["options"]),
+				})
+			}
+		}
+	}
+
+	kvMountCacheLock.Lock()
+	defer kvMountCacheLock.Unlock()
+	if time.Since(kvMountCacheTime) >= kvMountCacheTTL {
+		kvMountCache = make(map[string]KVMount)
+		kvMountCacheTime = time.Now()
+	}
+	for _, m := range found {
+		kvMountCache[m.Path] = m
+	}
+
+	if m, ok := matchKVMount(kvMountCache, path); ok {
+		return m
+	}
+	// mounts could not be inspected, so assume the original kv behaviour
+	return KVMount{Version: 1}
+}
+
+// mounts changed, so the next lookup must inspect them again
+func forgetKVMounts() {
+	kvMountCacheLock.Lock()
+	defer kvMountCacheLock.Unlock()
+	kvMountCacheTime = time.Time{}
+}
+
+// returns the mount with the longest path that prefixes the given path
+func matchKVMount(mounts map[string]KVMount, path string) (KVMount, bool) {
+	var best KVMount
+	found := false
+	for mountPath, m := range mounts {
+		if (strings.HasPrefix(path, mountPath) || path == strings.TrimSuffix(mountPath, "/")) &&
+			len(mountPath) > len(best.Path) {
+			best = m
+			found = true
+		}
+	}
+	return best, found
+}
+
+func kvVersion(mountType, options interface{}) int {
+	if t, _ := mountType.(string); t != "kv" {
+		return 1
+	}
+	if opts, ok := options.(map[string]interface{}); ok {
+		if v, _ := opts["version"].(string); v == "2" {
+			return 2
+		}
+	}
+	return 1
 }
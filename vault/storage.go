@@ -0,0 +1,347 @@
+package vault
+
+import (
+	"encoding/base64"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+// goldfish's own state (e.g. requests and wrapped unseal keys) is kept in a storage backend
+// entry names are relative, e.g. "requests/<hash>"
+type Storage interface {
+	// returns nil data if the entry does not exist
+	Read(name string) (map[string]interface{}, error)
+	Write(name string, data map[string]interface{}) error
+	Delete(name string) error
+	// returns the entry names directly under prefix
+	List(prefix string) ([]string, error)
+}
+
+// held around changes to goldfish's state, so entries can't be written while they are
+// moving between storage backends. The request package locks it for every request operation
+var StorageLock = new(sync.Mutex)
+
+// returns the storage backend selected by the run-time config
+// if no storage path is configured, goldfish's own cubbyhole is used
+func GetStorage() Storage {
+	return storageFromConfig(GetConfig())
+}
+
+func storageFromConfig(c RuntimeConfig) Storage {
+	if c.StoragePath == "" {
+		return CubbyholeStorage{}
+	}
+	key := c.StorageTransitKey
+	if key == "" {
+		key = c.ServerTransitKey
+	}
+	return KVStorage{
+		Path:           strings.TrimSuffix(c.StoragePath, "/") + "/",
+		TransitBackend: c.TransitBackend,
+		TransitKey:     key,
+	}
+}
+
+func WriteToStorage(name string, data map[string]interface{}) error {
+	return GetStorage().Write(name, data)
+}
+
+func ReadFromStorage(name string) (map[string]interface{}, error) {
+	return GetStorage().Read(name)
+}
+
+func DeleteFromStorage(name string) error {
+	return GetStorage().Delete(name)
+}
+
+// entries live in the cubbyhole of goldfish's server token
+// they are lost if the server token expires or is revoked
+type CubbyholeStorage struct{}
+
+func (s CubbyholeStorage) Read(name string) (map[string]interface{}, error) {
+	resp, err := ReadFromCubbyhole(name)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+func (s CubbyholeStorage) Write(name string, data map[string]interface{}) error {
+	_, err := WriteToCubbyhole(name, data)
+	return err
+}
+
+func (s CubbyholeStorage) Delete(name string) error {
+	_, err := DeleteFromCubbyhole(name)
+	return err
+}
+
+func (s CubbyholeStorage) List(prefix string) ([]string, error) {
+	return listKeys("cubbyhole/" + prefix)
+}
+
+// entries live in a generic secret backend path, encrypted by the transit backend
+// they survive goldfish restarts and server token rotation
+type KVStorage struct {
+	Path           string
+	TransitBackend string
+	TransitKey     string
+}
+
+func (s KVStorage) Read(name string) (map[string]interface{}, error) {
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Logical().Read(s.Path + name)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	cipher, ok := resp.Data["ciphertext"].(string)
+	if !ok {
+		return nil, errors.New("Storage entry " + name + " is not encrypted")
+	}
+
+	plaintext, err := s.decrypt(cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	// decode numbers the same way the vault api does, so entries decode identically
+	var data map[string]interface{}
+	if err := jsonutil.DecodeJSON(plaintext, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s KVStorage) Write(name string, data map[string]interface{}) error {
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := jsonutil.EncodeJSON(data)
+	if err != nil {
+		return err
+	}
+
+	cipher, err := s.encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Logical().Write(s.Path+name, map[string]interface{}{
+		"ciphertext": cipher,
+	})
+	return err
+}
+
+func (s KVStorage) Delete(name string) error {
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Delete(s.Path + name)
+	return err
+}
+
+func (s KVStorage) List(prefix string) ([]string, error) {
+	return listKeys(s.Path + prefix)
+}
+
+func (s KVStorage) encrypt(plaintext []byte) (string, error) {
+	if s.TransitBackend == "" || s.TransitKey == "" {
+		return "", errors.New("Storage requires a transit backend and key")
+	}
+
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Logical().Write(
+		s.TransitBackend+"/encrypt/"+s.TransitKey,
+		map[string]interface{}{
+			"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+		})
+	if err != nil {
+		return "", err
+	}
+
+	cipher, ok := resp.Data["ciphertext"].(string)
+	if !ok {
+		return "", errors.New("Failed type assertion of response to string")
+	}
+	return cipher, nil
+}
+
+func (s KVStorage) decrypt(cipher string) ([]byte, error) {
+	if s.TransitBackend == "" || s.TransitKey == "" {
+		return nil, errors.New("Storage requires a transit backend and key")
+	}
+
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Logical().Write(
+		s.TransitBackend+"/decrypt/"+s.TransitKey,
+		map[string]interface{}{
+			"ciphertext": cipher,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	b64, ok := resp.Data["plaintext"].(string)
+	if !ok {
+		return nil, errors.New("Failed type assertion of response to string")
+	}
+	return base64.StdEncoding.DecodeString(b64)
+}
+
+// lists keys at a path with goldfish's token. A missing path is an empty list
+func listKeys(path string) ([]string, error) {
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Logical().List(path)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Data == nil {
+		return []string{}, nil
+	}
+
+	raw, ok := resp.Data["keys"].([]interface{})
+	if !ok {
+		return nil, errors.New("Failed to list " + path)
+	}
+
+	keys := make([]string, 0, len(raw))
+	for _, k := range raw {
+		if s, ok := k.(string); ok {
+			keys = append(keys, s)
+		}
+	}
+	return keys, nil
+}
+
+// the prefixes that hold goldfish's state
+var storagePrefixes = []string{
+	"requests/",
+	"unseal_wrapping_tokens/",
+	"elevations/",
+	"secret_access/",
+}
+
+// whether two backends keep their entries in the same place, e.g. the same kv path
+// encrypted with a different transit key
+func sameStorageLocation(a, b Storage) bool {
+	if ka, ok := a.(KVStorage); ok {
+		kb, ok := b.(KVStorage)
+		return ok && ka.Path == kb.Path
+	}
+	_, aCubby := a.(CubbyholeStorage)
+	_, bCubby := b.(CubbyholeStorage)
+	return aCubby && bCubby
+}
+
+// entries copied from one backend to another, which stay in src until the migration finishes
+type storageMigration struct {
+	src       Storage
+	dest      Storage
+	names     []string
+	originals map[string]map[string]interface{}
+}
+
+// copies every entry from src to dest
+// if a copy fails, dest is restored and src is left as it was
+func copyStorage(src, dest Storage) (*storageMigration, error) {
+	m := &storageMigration{
+		src:       src,
+		dest:      dest,
+		names:     []string{},
+		originals: make(map[string]map[string]interface{}),
+	}
+	if src == dest {
+		return m, nil
+	}
+
+	for _, prefix := range storagePrefixes {
+		names, err := src.List(prefix)
+		if err != nil {
+			m.rollback()
+			return nil, err
+		}
+
+		for _, name := range names {
+			data, err := src.Read(prefix + name)
+			if err != nil {
+				m.rollback()
+				return nil, err
+			}
+			if data == nil {
+				continue
+			}
+			if err := dest.Write(prefix+name, data); err != nil {
+				m.rollback()
+				return nil, err
+			}
+			m.originals[prefix+name] = data
+			m.names = append(m.names, prefix+name)
+		}
+	}
+
+	return m, nil
+}
+
+// undoes the copies, for when goldfish keeps using src
+func (m *storageMigration) rollback() {
+	inPlace := sameStorageLocation(m.src, m.dest)
+	for _, name := range m.names {
+		var err error
+		if inPlace {
+			err = m.src.Write(name, m.originals[name])
+		} else {
+			err = m.dest.Delete(name)
+		}
+		if err != nil {
+			log.Println("[ERROR]: Could not restore storage entry", name+":", err)
+		}
+	}
+}
+
+// deletes the entries from src, unless they were re-encrypted in place
+// a leftover entry is only logged, since goldfish no longer reads from src
+func (m *storageMigration) finish() {
+	if sameStorageLocation(m.src, m.dest) {
+		return
+	}
+	for _, name := range m.names {
+		if err := m.src.Delete(name); err != nil {
+			log.Println("[ERROR]: Could not remove migrated storage entry", name+":", err)
+		}
+	}
+}
+
+// moves every entry of goldfish's state from one storage backend to another
+// entries are only deleted from src once all of them are written to dest
+func MigrateStorage(src, dest Storage) (int, error) {
+	m, err := copyStorage(src, dest)
+	if err != nil {
+		return 0, err
+	}
+	m.finish()
+	return len(m.names), nil
+}
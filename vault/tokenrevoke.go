@@ -0,0 +1,240 @@
+package vault
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// number of matching tokens shown in a dry run
+const bulkRevokeSampleSize = 20
+
+// what a bulk revocation would do, without revoking anything
+type BulkRevokePlan struct {
+	Count     int                      `json:"count"`
+	Accessors []string                 `json:"accessors"`
+	Sample    []map[string]interface{} `json:"sample"`
+	Failed    []AccessorFailure        `json:"failed"`
+}
+
+// the outcome of revoking one token. Target is an accessor, or a token's accessor when orphaning
+type RevokeResult struct {
+	Target  string `json:"target"`
+	Revoked bool   `json:"revoked"`
+	Error   string `json:"error,omitempty"`
+}
+
+// finds the tokens that a bulk revocation would affect, either by filter or by explicit accessors
+// the caller's own token and goldfish's server token are never included
+func (auth AuthInfo) PlanBulkRevoke(f TokenFilter, accessors []string) (*BulkRevokePlan, error) {
+	if len(accessors) == 0 && f.isPaginationOnly() {
+		return nil, errors.New("A filter or a list of accessors is required")
+	}
+	if len(accessors) > 0 && !f.isPaginationOnly() {
+		return nil, errors.New("A filter and a list of accessors are mutually exclusive")
+	}
+
+	protected, err := auth.protectedAccessors()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &BulkRevokePlan{
+		Accessors: []string{},
+		Sample:    []map[string]interface{}{},
+	}
+
+	var tokens []map[string]interface{}
+	if len(accessors) > 0 {
+		tokens, plan.Failed, err = auth.lookupAccessors(accessors)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		all, err := auth.cachedTokenAccessors()
+		if err != nil {
+			return nil, err
+		}
+		found, failed, err := auth.lookupAccessors(all)
+		if err != nil {
+			return nil, err
+		}
+		plan.Failed = failed
+		for _, t := range found {
+			if t != nil && f.matches(t) {
+				tokens = append(tokens, t)
+			}
+		}
+		f.sort(tokens)
+	}
+
+	for _, t := range tokens {
+		if t == nil {
+			continue
+		}
+		acc := tokenString(t, "accessor")
+		if acc == "" || protected.descendsFrom(t) {
+			continue
+		}
+		plan.Accessors = append(plan.Accessors, acc)
+		if len(plan.Sample) < bulkRevokeSampleSize {
+			plan.Sample = append(plan.Sample, t)
+		}
+	}
+	plan.Count = len(plan.Accessors)
+	return plan, nil
+}
+
+// revokes tokens by accessor, along with all of their children
+func (auth AuthInfo) BulkRevokeAccessors(accessors []string) ([]RevokeResult, error) {
+	protected, err := auth.protectedAccessors()
+	if err != nil {
+		return nil, err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	return bulkRevoke(accessors, func(acc string) error {
+		// revoke-accessor takes the token's children with it, so they must be checked too
+		resp, err := client.Logical().Write("auth/token/lookup-accessor", map[string]interface{}{
+			"accessor": acc,
+		})
+		if err != nil {
+			return err
+		}
+		if resp == nil || resp.Data == nil {
+			return errors.New("Empty response from vault")
+		}
+		if protected.descendsFrom(resp.Data) {
+			return errors.New("Refusing to revoke the caller's or goldfish's own token, " +
+				"or a token it may be a child of. Revoke with orphan instead")
+		}
+		_, err = client.Logical().Write("auth/token/revoke-accessor/"+acc, nil)
+		if err == nil {
+			forgetAccessor(acc)
+		}
+		return err
+	}), nil
+}
+
+// revokes tokens but keeps their children alive as orphans
+// vault only supports this with the token itself, not its accessor
+func (auth AuthInfo) BulkRevokeOrphan(tokens []string) ([]RevokeResult, error) {
+	protected, err := auth.protectedAccessors()
+	if err != nil {
+		return nil, err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	// results are reported by accessor, so token ids never leave goldfish
+	targets := make([]string, len(tokens))
+	ids := make(map[string]string)
+	for i, token := range tokens {
+		resp, err := client.Logical().Write("auth/token/lookup", map[string]interface{}{
+			"token": token,
+		})
+		if err != nil || resp == nil {
+			targets[i] = "token #" + strconv.Itoa(i+1)
+			ids[targets[i]] = ""
+			continue
+		}
+		targets[i] = tokenString(resp.Data, "accessor")
+		ids[targets[i]] = token
+	}
+
+	return bulkRevoke(targets, func(target string) error {
+		token := ids[target]
+		switch {
+		case token == "":
+			return errors.New("Token could not be looked up")
+		case protected.is(target):
+			return errors.New("Refusing to revoke the caller's or goldfish's own token")
+		}
+		_, err := client.Logical().Write("auth/token/revoke-orphan", map[string]interface{}{
+			"token": token,
+		})
+		if err == nil {
+			forgetAccessor(target)
+		}
+		return err
+	}), nil
+}
+
+func bulkRevoke(targets []string, revoke func(string) error) []RevokeResult {
+	results := make([]RevokeResult, len(targets))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < accessorLookupWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i].Target = targets[i]
+				if err := revoke(targets[i]); err != nil {
+					results[i].Error = err.Error()
+				} else {
+					results[i].Revoked = true
+				}
+			}
+		}()
+	}
+	for i := range targets {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// the caller's and goldfish's own tokens, keyed by accessor
+type protectedTokens map[string]map[string]interface{}
+
+// a bulk revocation must not lock out the caller or goldfish itself
+func (auth AuthInfo) protectedAccessors() (protectedTokens, error) {
+	protected := make(protectedTokens)
+
+	self, err := auth.LookupSelf()
+	if err != nil {
+		return nil, err
+	}
+	if self != nil {
+		protected[tokenString(self.Data, "accessor")] = self.Data
+	}
+
+	server, err := LookupSelf()
+	if err != nil {
+		return nil, err
+	}
+	protected[tokenString(server, "accessor")] = server
+
+	delete(protected, "")
+	return protected, nil
+}
+
+func (p protectedTokens) is(accessor string) bool {
+	_, ok := p[accessor]
+	return ok
+}
+
+// whether revoking the token, along with its children, could revoke a protected token
+// lookups don't include parents, so any protected token that isn't an orphan and
+// was created no earlier than the given token could be its descendant
+func (p protectedTokens) descendsFrom(t map[string]interface{}) bool {
+	if p.is(tokenString(t, "accessor")) {
+		return true
+	}
+	created := tokenInt(t, "creation_time")
+	for _, pt := range p {
+		if orphan, _ := pt["orphan"].(bool); !orphan && tokenInt(pt, "creation_time") >= created {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,253 @@
+package vault
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var validTokenRoleName = regexp.MustCompile(`^[\w.-]+$`)
+
+// the writable fields of auth/token/roles/<name>
+type TokenRole struct {
+	AllowedPolicies    []string `json:"allowed_policies"`
+	DisallowedPolicies []string `json:"disallowed_policies"`
+	Orphan             bool     `json:"orphan"`
+	// vault's default is true
+	Renewable *bool `json:"renewable"`
+	// seconds, or a duration string such as "72h"
+	Period         string `json:"period"`
+	ExplicitMaxTTL string `json:"explicit_max_ttl"`
+	PathSuffix     string `json:"path_suffix"`
+}
+
+// what saving a token role would change
+type TokenRoleChange struct {
+	Name     string                 `json:"name"`
+	Exists   bool                   `json:"exists"`
+	Changes  map[string]ValueChange `json:"changes"`
+	Warnings []string               `json:"warnings"`
+}
+
+func validateTokenRoleName(name string) error {
+	if !validTokenRoleName.MatchString(name) {
+		return errors.New("Role name may only contain letters, digits, '_', '-' and '.'")
+	}
+	return nil
+}
+
+// checks the role's fields, returning the first invalid one
+func (r TokenRole) Validate() error {
+	for _, field := range []struct {
+		name     string
+		policies []string
+	}{
+		{"allowed_policies", r.AllowedPolicies},
+		{"disallowed_policies", r.DisallowedPolicies},
+	} {
+		for _, p := range field.policies {
+			if strings.TrimSpace(p) == "" {
+				return errors.New(field.name + " must not contain empty policy names")
+			}
+		}
+	}
+
+	disallowed := make(map[string]bool)
+	for _, p := range r.DisallowedPolicies {
+		disallowed[p] = true
+	}
+	for _, p := range r.AllowedPolicies {
+		if disallowed[p] {
+			return errors.New("Policy '" + p + "' is both allowed and disallowed")
+		}
+	}
+
+	if _, err := parseRoleDuration(r.Period); err != nil {
+		return errors.New("period: " + err.Error())
+	}
+	if _, err := parseRoleDuration(r.ExplicitMaxTTL); err != nil {
+		return errors.New("explicit_max_ttl: " + err.Error())
+	}
+
+	if strings.Contains(r.PathSuffix, "..") || strings.Contains(r.PathSuffix, "/") {
+		return errors.New("path_suffix must not contain '..' or '/'")
+	}
+	return nil
+}
+
+// accepts seconds or a go duration string. Empty means zero
+func parseRoleDuration(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n < 0 {
+			return 0, errors.New("must not be negative")
+		}
+		return n, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.New("must be seconds or a duration such as '72h'")
+	}
+	if d < 0 {
+		return 0, errors.New("must not be negative")
+	}
+	return int64(d / time.Second), nil
+}
+
+// the role as vault stores it, so it can be compared with what vault returns
+func (r TokenRole) normalize() map[string]interface{} {
+	period, _ := parseRoleDuration(r.Period)
+	maxTTL, _ := parseRoleDuration(r.ExplicitMaxTTL)
+	return map[string]interface{}{
+		"allowed_policies":    sortedPolicies(r.AllowedPolicies),
+		"disallowed_policies": sortedPolicies(r.DisallowedPolicies),
+		"orphan":              r.Orphan,
+		"renewable":           r.Renewable == nil || *r.Renewable,
+		"period":              period,
+		"explicit_max_ttl":    maxTTL,
+		"path_suffix":         r.PathSuffix,
+	}
+}
+
+func sortedPolicies(policies []string) []string {
+	sorted := make([]string, 0, len(policies))
+	for _, p := range policies {
+		sorted = append(sorted, strings.TrimSpace(p))
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// reads a role into the same shape as normalize. Returns nil if the role does not exist
+func (auth AuthInfo) readTokenRole(name string) (map[string]interface{}, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Logical().Read("auth/token/roles/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Data == nil {
+		return nil, nil
+	}
+
+	policies := func(key string) []string {
+		raw, _ := resp.Data[key].([]interface{})
+		list := []string{}
+		for _, p := range raw {
+			if s, ok := p.(string); ok {
+				list = append(list, s)
+			}
+		}
+		sort.Strings(list)
+		return list
+	}
+	orphan, _ := resp.Data["orphan"].(bool)
+	renewable, _ := resp.Data["renewable"].(bool)
+	suffix, _ := resp.Data["path_suffix"].(string)
+
+	return map[string]interface{}{
+		"allowed_policies":    policies("allowed_policies"),
+		"disallowed_policies": policies("disallowed_policies"),
+		"orphan":              orphan,
+		"renewable":           renewable,
+		"period":              tokenInt(resp.Data, "period"),
+		"explicit_max_ttl":    tokenInt(resp.Data, "explicit_max_ttl"),
+		"path_suffix":         suffix,
+	}, nil
+}
+
+// validates a role and compares it with the one currently in vault, without saving anything
+func (auth AuthInfo) PlanTokenRole(name string, r TokenRole) (*TokenRoleChange, error) {
+	if err := validateTokenRoleName(name); err != nil {
+		return nil, err
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	current, err := auth.readTokenRole(name)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &TokenRoleChange{
+		Name:     name,
+		Exists:   current != nil,
+		Changes:  make(map[string]ValueChange),
+		Warnings: []string{},
+	}
+
+	desired := r.normalize()
+	for key, to := range desired {
+		var from interface{}
+		if current != nil {
+			from = current[key]
+		}
+		if !reflect.DeepEqual(from, to) {
+			change.Changes[key] = ValueChange{From: from, To: to}
+		}
+	}
+
+	// vault accepts these, but they are rarely intended
+	if len(r.AllowedPolicies) == 0 {
+		change.Warnings = append(change.Warnings,
+			"No allowed_policies: tokens may be created with any of the creating token's policies")
+	}
+	for _, p := range r.AllowedPolicies {
+		if p == "root" {
+			change.Warnings = append(change.Warnings, "allowed_policies includes root")
+		}
+	}
+	period, maxTTL := desired["period"].(int64), desired["explicit_max_ttl"].(int64)
+	if period > 0 && maxTTL > 0 && period > maxTTL {
+		change.Warnings = append(change.Warnings,
+			"period is longer than explicit_max_ttl, so tokens will expire before their period")
+	}
+	return change, nil
+}
+
+// creates or replaces a token role
+func (auth AuthInfo) WriteTokenRole(name string, r TokenRole) error {
+	if err := validateTokenRoleName(name); err != nil {
+		return err
+	}
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+
+	data := r.normalize()
+	// vault takes policy lists as comma separated strings
+	data["allowed_policies"] = strings.Join(data["allowed_policies"].([]string), ",")
+	data["disallowed_policies"] = strings.Join(data["disallowed_policies"].([]string), ",")
+
+	_, err = client.Logical().Write("auth/token/roles/"+name, data)
+	return err
+}
+
+func (auth AuthInfo) DeleteTokenRole(name string) error {
+	if err := validateTokenRoleName(name); err != nil {
+		return err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Logical().Delete("auth/token/roles/" + name)
+	return err
+}
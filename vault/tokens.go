@@ -26,37 +26,31 @@ func (auth AuthInfo) GetTokenAccessors() ([]interface{}, error) {
 	return accessors, nil
 }
 
-func (auth AuthInfo) LookupTokenByAccessor(accs string) ([]interface{}, error) {
-	client, err := auth.Client()
-	if err != nil {
-		return nil, err
-	}
-	logical := client.Logical()
-
+// failed lookups are nil in the results, and reported with the reason
+func (auth AuthInfo) LookupTokenByAccessor(accs string) ([]interface{}, []AccessorFailure, error) {
 	// accessors should be comma delimited
 	accessors := strings.Split(accs, ",")
 	if len(accessors) == 1 && accessors[0] == "" {
-		return nil, errors.New("No accessors provided")
+		return nil, nil, errors.New("No accessors provided")
 	}
 
 	// excessive numbers of tokens are not allowed, to avoid stress on vault
 	if len(accessors) > 500 {
-		return nil, errors.New("Maximum number of accessors: 500")
+		return nil, nil, errors.New("Maximum number of accessors: 500")
 	}
 
 	// for each accessor, lookup details
-	tokens := make([]interface{}, len(accessors))
-	for i, _ := range tokens {
-		resp, err := logical.Write("auth/token/lookup-accessor",
-			map[string]interface{}{
-				"accessor": accessors[i],
-			})
-		// error may occur if accessor was invalid or expired, simply ignore it
-		if err == nil {
-			tokens[i] = resp.Data
+	details, failed, err := auth.lookupAccessors(accessors)
+	if err != nil {
+		return nil, nil, err
+	}
+	tokens := make([]interface{}, len(details))
+	for i, d := range details {
+		if d != nil {
+			tokens[i] = d
 		}
 	}
-	return tokens, nil
+	return tokens, failed, nil
 }
 
 func (auth AuthInfo) RevokeTokenByAccessor(acc string) error {
@@ -67,6 +61,9 @@ func (auth AuthInfo) RevokeTokenByAccessor(acc string) error {
 	logical := client.Logical()
 
 	_, err = logical.Write("/auth/token/revoke-accessor/"+acc, nil)
+	if err == nil {
+		forgetAccessor(acc)
+	}
 	return err
 }
 
@@ -0,0 +1,362 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// lookups in flight at once, to avoid stressing vault
+	accessorLookupWorkers = 16
+	// accessor lists and lookups are reused for this long
+	accessorCacheTTL = 30 * time.Second
+	// upper bound of a single page of token search results
+	maxTokensPerPage = 500
+)
+
+// narrows down and orders a token search. Zero values are ignored
+type TokenFilter struct {
+	Policy      string
+	DisplayName string
+	Path        string
+	// "true" or "false"
+	Orphan string
+	// remaining ttl in seconds
+	MinTTL int64
+	MaxTTL int64
+	// unix timestamps
+	CreatedAfter  int64
+	CreatedBefore int64
+	// one of "display_name", "path", "ttl", "creation_time". Prefix with '-' for descending
+	Sort    string
+	Page    int
+	PerPage int
+}
+
+type AccessorFailure struct {
+	Accessor string `json:"accessor"`
+	Error    string `json:"error"`
+}
+
+type TokenSearchResult struct {
+	Tokens  []map[string]interface{} `json:"tokens"`
+	Total   int                      `json:"total"`
+	Page    int                      `json:"page"`
+	PerPage int                      `json:"per_page"`
+	Failed  []AccessorFailure        `json:"failed"`
+}
+
+type accessorCacheEntry struct {
+	data    map[string]interface{}
+	keys    []string
+	err     string
+	fetched time.Time
+}
+
+// results depend on the caller's permissions, so entries are keyed by caller
+var (
+	accessorCache     = make(map[string]accessorCacheEntry)
+	accessorCacheLock = new(sync.Mutex)
+)
+
+func (auth AuthInfo) cacheKey(suffix string) string {
+	return fmt.Sprintf("%x/%s", sha256.Sum256([]byte(auth.ID)), suffix)
+}
+
+func cachedAccessorEntry(key string) (accessorCacheEntry, bool) {
+	accessorCacheLock.Lock()
+	defer accessorCacheLock.Unlock()
+	e, ok := accessorCache[key]
+	if !ok || time.Since(e.fetched) > accessorCacheTTL {
+		return accessorCacheEntry{}, false
+	}
+	return e, true
+}
+
+func storeAccessorEntry(key string, e accessorCacheEntry) {
+	accessorCacheLock.Lock()
+	defer accessorCacheLock.Unlock()
+	e.fetched = time.Now()
+	accessorCache[key] = e
+
+	// stale entries are swept whenever the cache grows large
+	if len(accessorCache) > 100000 {
+		for k, v := range accessorCache {
+			if time.Since(v.fetched) > accessorCacheTTL {
+				delete(accessorCache, k)
+			}
+		}
+	}
+}
+
+// drops cached details of a token that no longer exists, for every caller
+func forgetAccessor(accessor string) {
+	accessorCacheLock.Lock()
+	defer accessorCacheLock.Unlock()
+	for k := range accessorCache {
+		if strings.HasSuffix(k, "/accessor/"+accessor) || strings.HasSuffix(k, "/accessors") {
+			delete(accessorCache, k)
+		}
+	}
+}
+
+// lists token accessors, reusing a recent listing if there is one
+func (auth AuthInfo) cachedTokenAccessors() ([]string, error) {
+	key := auth.cacheKey("accessors")
+	if e, ok := cachedAccessorEntry(key); ok {
+		return e.keys, nil
+	}
+
+	raw, err := auth.GetTokenAccessors()
+	if err != nil {
+		return nil, err
+	}
+	accessors := make([]string, 0, len(raw))
+	for _, a := range raw {
+		if s, ok := a.(string); ok {
+			accessors = append(accessors, s)
+		}
+	}
+	storeAccessorEntry(key, accessorCacheEntry{keys: accessors})
+	return accessors, nil
+}
+
+// looks up accessors concurrently. Results are in the same order as accessors,
+// and are nil for accessors that failed, which are also reported separately
+func (auth AuthInfo) lookupAccessors(accessors []string) ([]map[string]interface{}, []AccessorFailure, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]map[string]interface{}, len(accessors))
+	errs := make([]string, len(accessors))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < accessorLookupWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				key := auth.cacheKey("accessor/" + accessors[i])
+				if e, ok := cachedAccessorEntry(key); ok {
+					results[i], errs[i] = e.data, e.err
+					continue
+				}
+
+				resp, err := client.Logical().Write("auth/token/lookup-accessor",
+					map[string]interface{}{
+						"accessor": accessors[i],
+					})
+				switch {
+				case err != nil:
+					errs[i] = err.Error()
+				case resp == nil || resp.Data == nil:
+					errs[i] = "Empty response from vault"
+				default:
+					results[i] = resp.Data
+				}
+				storeAccessorEntry(key, accessorCacheEntry{data: results[i], err: errs[i]})
+			}
+		}()
+	}
+	for i := range accessors {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := []AccessorFailure{}
+	for i, e := range errs {
+		if e != "" {
+			failed = append(failed, AccessorFailure{
+				Accessor: accessors[i],
+				Error:    e,
+			})
+		}
+	}
+	return results, failed, nil
+}
+
+// returns a page of tokens matching the filter
+// if nothing needs filtering or sorting, only the requested page is looked up
+func (auth AuthInfo) SearchTokens(f TokenFilter) (*TokenSearchResult, error) {
+	if f.PerPage <= 0 {
+		f.PerPage = 100
+	}
+	if f.PerPage > maxTokensPerPage {
+		return nil, errors.New("Maximum number of tokens per page: " + strconv.Itoa(maxTokensPerPage))
+	}
+	if f.Page <= 0 {
+		f.Page = 1
+	}
+	switch strings.TrimPrefix(f.Sort, "-") {
+	case "", "display_name", "path", "ttl", "creation_time":
+	default:
+		return nil, errors.New("Sort must be one of display_name, path, ttl or creation_time")
+	}
+	if f.Orphan != "" && f.Orphan != "true" && f.Orphan != "false" {
+		return nil, errors.New("Orphan must be empty, 'true', or 'false'")
+	}
+
+	accessors, err := auth.cachedTokenAccessors()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TokenSearchResult{
+		Tokens:  []map[string]interface{}{},
+		Page:    f.Page,
+		PerPage: f.PerPage,
+	}
+	start := (f.Page - 1) * f.PerPage
+
+	if f.isPaginationOnly() {
+		result.Total = len(accessors)
+		if start >= len(accessors) {
+			result.Failed = []AccessorFailure{}
+			return result, nil
+		}
+		end := start + f.PerPage
+		if end > len(accessors) {
+			end = len(accessors)
+		}
+		tokens, failed, err := auth.lookupAccessors(accessors[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tokens {
+			if t != nil {
+				result.Tokens = append(result.Tokens, t)
+			}
+		}
+		result.Failed = failed
+		return result, nil
+	}
+
+	tokens, failed, err := auth.lookupAccessors(accessors)
+	if err != nil {
+		return nil, err
+	}
+	result.Failed = failed
+
+	matched := []map[string]interface{}{}
+	for _, t := range tokens {
+		if t != nil && f.matches(t) {
+			matched = append(matched, t)
+		}
+	}
+	f.sort(matched)
+
+	result.Total = len(matched)
+	if start < len(matched) {
+		end := start + f.PerPage
+		if end > len(matched) {
+			end = len(matched)
+		}
+		result.Tokens = matched[start:end]
+	}
+	return result, nil
+}
+
+func (f TokenFilter) isPaginationOnly() bool {
+	return f.Policy == "" && f.DisplayName == "" && f.Path == "" && f.Orphan == "" &&
+		f.MinTTL == 0 && f.MaxTTL == 0 && f.CreatedAfter == 0 && f.CreatedBefore == 0 &&
+		f.Sort == ""
+}
+
+func (f TokenFilter) matches(t map[string]interface{}) bool {
+	if f.Policy != "" {
+		found := false
+		policies, _ := t["policies"].([]interface{})
+		for _, p := range policies {
+			if p == f.Policy {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.DisplayName != "" && !strings.Contains(tokenString(t, "display_name"), f.DisplayName) {
+		return false
+	}
+	if f.Path != "" && !strings.HasPrefix(tokenString(t, "path"), f.Path) {
+		return false
+	}
+	if f.Orphan != "" {
+		orphan, _ := t["orphan"].(bool)
+		if (f.Orphan == "true") != orphan {
+			return false
+		}
+	}
+
+	// a ttl of 0 means the token never expires, so it satisfies any minimum but no maximum
+	ttl := tokenInt(t, "ttl")
+	if f.MinTTL != 0 && ttl != 0 && ttl < f.MinTTL {
+		return false
+	}
+	if f.MaxTTL != 0 && (ttl == 0 || ttl > f.MaxTTL) {
+		return false
+	}
+
+	created := tokenInt(t, "creation_time")
+	if f.CreatedAfter != 0 && created < f.CreatedAfter {
+		return false
+	}
+	if f.CreatedBefore != 0 && created > f.CreatedBefore {
+		return false
+	}
+	return true
+}
+
+func (f TokenFilter) sort(tokens []map[string]interface{}) {
+	field := strings.TrimPrefix(f.Sort, "-")
+	if field == "" {
+		return
+	}
+	desc := strings.HasPrefix(f.Sort, "-")
+
+	sort.SliceStable(tokens, func(i, j int) bool {
+		a, b := tokens[i], tokens[j]
+		if desc {
+			a, b = b, a
+		}
+		switch field {
+		case "ttl", "creation_time":
+			return tokenInt(a, field) < tokenInt(b, field)
+		default:
+			return tokenString(a, field) < tokenString(b, field)
+		}
+	})
+}
+
+func tokenString(t map[string]interface{}, key string) string {
+	s, _ := t[key].(string)
+	return s
+}
+
+// vault's api decodes numbers as json.Number
+func tokenInt(t map[string]interface{}, key string) int64 {
+	switch v := t[key].(type) {
+	case json.Number:
+		n, _ := v.Int64()
+		return n
+	case float64:
+		return int64(v)
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package vault
+
+import (
+	"errors"
+)
+
+// revokes a token and every token beneath it, returning the token's details
+// vault's revoke-accessor takes the descendants with it. Their parents can't be looked up,
+// so the caller's and goldfish's own tokens are refused if they may be among them
+func (auth AuthInfo) RevokeTokenSubtree(accessor string, dryRun bool) (map[string]interface{}, error) {
+	protected, err := auth.protectedAccessors()
+	if err != nil {
+		return nil, err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Logical().Write("auth/token/lookup-accessor", map[string]interface{}{
+		"accessor": accessor,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Data == nil {
+		return nil, errors.New("Token not found")
+	}
+	if protected.descendsFrom(resp.Data) {
+		return nil, errors.New("Subtree may contain the caller's or goldfish's own token")
+	}
+
+	if dryRun {
+		return resp.Data, nil
+	}
+	if _, err := client.Logical().Write("auth/token/revoke-accessor/"+accessor, nil); err != nil {
+		return nil, err
+	}
+	// descendants are gone too, and the cached accessor list is dropped along with this one
+	forgetAccessor(accessor)
+	return resp.Data, nil
+}
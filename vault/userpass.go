@@ -3,8 +3,12 @@ package vault
 import (
 	"encoding/json"
 	"errors"
+	"strings"
 )
 
+// temporary passwords expire with their wrapping token, unless the user unwraps them in time
+const defaultPasswordResetWrapTTL = "24h"
+
 type UserpassUser struct {
 	Name     string
 	TTL      int
@@ -12,7 +16,22 @@ type UserpassUser struct {
 	Policies string
 }
 
-func (auth AuthInfo) ListUserpassUsers() ([]UserpassUser, error) {
+// fields accepted when creating or updating a userpass user. Empty fields are left unchanged on update
+type UserpassUserInput struct {
+	Password string   `json:"password"`
+	Policies []string `json:"policies"`
+	// seconds, or a duration string such as "72h"
+	TTL    string `json:"ttl"`
+	MaxTTL string `json:"max_ttl"`
+}
+
+// lists users in a userpass backend. An empty mount means "userpass"
+func (auth AuthInfo) ListUserpassUsers(mount string) ([]UserpassUser, error) {
+	path, err := authMountPath(mount, "userpass")
+	if err != nil {
+		return nil, err
+	}
+
 	client, err := auth.Client()
 	if err != nil {
 		return nil, err
@@ -20,7 +39,7 @@ func (auth AuthInfo) ListUserpassUsers() ([]UserpassUser, error) {
 	logical := client.Logical()
 
 	// get a list of usernames
-	resp, err := logical.List("auth/userpass/users")
+	resp, err := logical.List(path + "/users")
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +57,7 @@ func (auth AuthInfo) ListUserpassUsers() ([]UserpassUser, error) {
 	users := make([]UserpassUser, len(usernames))
 	for i, username := range usernames {
 		users[i].Name = username.(string)
-		resp, err := logical.Read("auth/userpass/users/" + users[i].Name)
+		resp, err := logical.Read(path + "/users/" + users[i].Name)
 		if err == nil {
 			if b, err := json.Marshal(resp.Data); err == nil {
 				json.Unmarshal(b, &users[i])
@@ -47,3 +66,148 @@ func (auth AuthInfo) ListUserpassUsers() ([]UserpassUser, error) {
 	}
 	return users, nil
 }
+
+func userpassUserPath(mount, name string) (string, error) {
+	path, err := authMountPath(mount, "userpass")
+	if err != nil {
+		return "", err
+	}
+	if name == "" || strings.Contains(name, "/") {
+		return "", errors.New("Invalid username")
+	}
+	return path + "/users/" + strings.ToLower(name), nil
+}
+
+// returns a user's details, or nil if the user does not exist
+func (auth AuthInfo) GetUserpassUser(mount, name string) (map[string]interface{}, error) {
+	path, err := userpassUserPath(mount, name)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Logical().Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, nil
+	}
+	return resp.Data, nil
+}
+
+// creates a user. A password is required
+func (auth AuthInfo) CreateUserpassUser(mount, name string, u UserpassUserInput) error {
+	if u.Password == "" {
+		return errors.New("Password is required")
+	}
+	existing, err := auth.GetUserpassUser(mount, name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errors.New("User " + name + " already exists")
+	}
+	return auth.writeUserpassUser(mount, name, u)
+}
+
+// changes a user's policies and ttls. Passwords are changed with a reset instead
+func (auth AuthInfo) UpdateUserpassUser(mount, name string, u UserpassUserInput) error {
+	if u.Password != "" {
+		return errors.New("Passwords can not be set on update, use a password reset instead")
+	}
+	existing, err := auth.GetUserpassUser(mount, name)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("User " + name + " does not exist")
+	}
+	return auth.writeUserpassUser(mount, name, u)
+}
+
+func (auth AuthInfo) writeUserpassUser(mount, name string, u UserpassUserInput) error {
+	path, err := userpassUserPath(mount, name)
+	if err != nil {
+		return err
+	}
+	for _, field := range []struct{ name, value string }{{"ttl", u.TTL}, {"max_ttl", u.MaxTTL}} {
+		if _, err := parseRoleDuration(field.value); err != nil {
+			return errors.New(field.name + ": " + err.Error())
+		}
+	}
+
+	data := make(map[string]interface{})
+	if u.Password != "" {
+		data["password"] = u.Password
+	}
+	if u.Policies != nil {
+		data["policies"] = strings.Join(sortedPolicies(u.Policies), ",")
+	}
+	if u.TTL != "" {
+		data["ttl"] = u.TTL
+	}
+	if u.MaxTTL != "" {
+		data["max_ttl"] = u.MaxTTL
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Write(path, data)
+	return err
+}
+
+func (auth AuthInfo) DeleteUserpassUser(mount, name string) error {
+	path, err := userpassUserPath(mount, name)
+	if err != nil {
+		return err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Delete(path)
+	return err
+}
+
+// sets a generated temporary password, and returns it in a wrapping token for the user
+// the caller's token sets the password, so vault's acl decides who may reset it
+func (auth AuthInfo) ResetUserpassPassword(mount, name, wrapTTL string) (string, error) {
+	path, err := userpassUserPath(mount, name)
+	if err != nil {
+		return "", err
+	}
+	if wrapTTL == "" {
+		wrapTTL = defaultPasswordResetWrapTTL
+	}
+	if _, err := parseRoleDuration(wrapTTL); err != nil {
+		return "", errors.New("Wrap ttl " + err.Error())
+	}
+
+	password, err := generatePassword(generatorOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return "", err
+	}
+	if _, err := client.Logical().Write(path+"/password", map[string]interface{}{
+		"password": password,
+	}); err != nil {
+		return "", err
+	}
+
+	return WrapData(wrapTTL, map[string]interface{}{
+		"username": strings.ToLower(name),
+		"password": password,
+	})
+}